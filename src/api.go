@@ -1,122 +1,70 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"io/ioutil"
-	"net/http"
-	"strings"
+	"net"
 	"time"
 
-	"github.com/denwerk/moco/src/types"
+	"github.com/denwerk/moco/src/api"
 )
 
-func makeRequest(cfg *Config, method, path string, body []byte) ([]byte, error) {
-	url := fmt.Sprintf("https://%s.mocoapp.com/api/v1/%s", cfg.MocoDomain, path)
-	LogAPIRequest(method, url, body)
+// version is reported in the User-Agent header
+var version = "dev"
 
-	var bodyReader io.Reader
-	if body != nil {
-		bodyReader = strings.NewReader(string(body))
-	}
+// requestTimeout bounds every API call made from the TUI
+const requestTimeout = 15 * time.Second
 
-	req, err := http.NewRequest(method, url, bodyReader)
-	if err != nil {
-		LogAPIError(err)
-		return nil, err
-	}
+// apiLogger forwards client traces to the API log file
+type apiLogger struct{}
 
-	req.Header.Set("Authorization", "Token "+cfg.MocoAPIKey)
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
+func (apiLogger) LogRequest(method, url string, body []byte) { LogAPIRequest(method, url, body) }
+func (apiLogger) LogResponse(statusCode int, body []byte)    { LogAPIResponse(statusCode, body) }
+func (apiLogger) LogError(err error)                         { LogAPIError(err) }
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		LogAPIError(err)
-		return nil, err
+func newClient(cfg *Config, extra ...api.Option) (*api.Client, error) {
+	opts := []api.Option{
+		api.WithUserAgent("moco-tui/" + version),
+		api.WithLogger(apiLogger{}),
 	}
-	defer resp.Body.Close()
-
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		LogAPIError(err)
-		return nil, err
-	}
-
-	LogAPIResponse(resp.StatusCode, responseBody)
-
-	if resp.StatusCode >= 400 {
-		// Try to parse error message from response
-		var errorResponse struct {
-			Error string `json:"error"`
-		}
-		if err := json.Unmarshal(responseBody, &errorResponse); err == nil && errorResponse.Error != "" {
-			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, errorResponse.Error)
-		}
-		// If we can't parse the error message, return the raw response
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(responseBody))
+	if cfg.MocoBaseURL != "" {
+		opts = append(opts, api.WithBaseURL(cfg.MocoBaseURL))
 	}
-
-	return responseBody, nil
+	opts = append(opts, extra...)
+	return api.NewClient(cfg.MocoDomain, cfg.MocoAPIKey, opts...)
 }
 
-func fetchProjects(cfg *Config) ([]types.Project, error) {
-	body, err := makeRequest(cfg, "GET", "projects/assigned", nil)
-	if err != nil {
-		return nil, err
-	}
-
-	var projects []types.Project
-	if err := json.Unmarshal(body, &projects); err != nil {
-		LogAPIError(err)
-		return nil, fmt.Errorf("error unmarshaling projects: %v", err)
-	}
-
-	return projects, nil
+func requestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), requestTimeout)
 }
 
-func fetchTimeEntries(cfg *Config, date string) ([]types.TimeEntry, error) {
-	endDate, err := time.Parse("2006-01-02", date)
-	if err != nil {
-		LogAPIError(err)
-		return nil, err
-	}
-
-	startDate := endDate.AddDate(0, 0, -6)
-	startDateStr := startDate.Format("2006-01-02")
-	endDateStr := endDate.Format("2006-01-02")
-
-	path := fmt.Sprintf("activities?from=%s&to=%s", startDateStr, endDateStr)
-	body, err := makeRequest(cfg, "GET", path, nil)
-	if err != nil {
-		return nil, err
+// describeError turns client errors into messages that fit the status line
+func describeError(err error) string {
+	var apiErr *api.Error
+	var orphanErr *api.OrphanedEntryError
+	switch {
+	case errors.As(err, &orphanErr):
+		return fmt.Sprintf("%s; entry #%d on %s was left booked with 0h, delete it or book time on it",
+			describeError(orphanErr.Err), orphanErr.Entry.ID, orphanErr.Entry.Date)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "request timed out"
+	case errors.As(err, &apiErr) && apiErr.IsRateLimited():
+		if apiErr.RetryAfter > 0 {
+			return fmt.Sprintf("rate limited by Moco, try again in %s", apiErr.RetryAfter.Round(time.Second))
+		}
+		return "rate limited by Moco, try again shortly"
+	case errors.As(err, &apiErr) && apiErr.IsTemporary():
+		return fmt.Sprintf("Moco is unavailable (status %d), try again later", apiErr.StatusCode)
+	case errors.As(err, &apiErr):
+		if apiErr.Message != "" {
+			return apiErr.Message
+		}
+		return fmt.Sprintf("request failed with status %d", apiErr.StatusCode)
 	}
-
-	var entries []types.TimeEntry
-	if err := json.Unmarshal(body, &entries); err != nil {
-		LogAPIError(err)
-		return nil, fmt.Errorf("error unmarshaling time entries: %v", err)
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return fmt.Sprintf("network error: %v", err)
 	}
-
-	return entries, nil
-}
-
-func submitTimeEntry(cfg *Config, entry types.TimeEntry) error {
-	jsonData, err := json.Marshal(entry)
-	if err != nil {
-		LogAPIError(err)
-		return err
-	}
-
-	_, err = makeRequest(cfg, "POST", "activities", jsonData)
-	return err
-}
-
-func deleteTimeEntry(cfg *Config, id int) error {
-	path := fmt.Sprintf("activities/%d", id)
-	_, err := makeRequest(cfg, "DELETE", path, nil)
-	return err
+	return err.Error()
 }
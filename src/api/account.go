@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// mocoHostSuffix is the domain Moco accounts live under
+const mocoHostSuffix = ".mocoapp.com"
+
+// subdomainPattern matches a single host name label such as "acme"
+var subdomainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// Account tells where the API of a Moco account lives
+type Account struct {
+	Name    string // The mocoapp.com subdomain, or the host of a custom API root
+	BaseURL string // The API root, ending in a slash
+}
+
+// ParseAccount accepts the ways people write down their Moco account: a
+// bare mocoapp.com subdomain ("acme"), a host ("acme.mocoapp.com",
+// "moco.example.com:8443") or a URL ("https://acme.mocoapp.com/"). URLs
+// with a path are taken as the API root, for proxies, gateways and local
+// stand-ins; without one the standard /api/v1/ root is used. mocoapp.com
+// accounts are always reached over HTTPS at their standard root, so a URL
+// copied from the browser works too.
+func ParseAccount(input string) (Account, error) {
+	raw := strings.TrimSpace(input)
+	if raw == "" {
+		return Account{}, fmt.Errorf("no account given")
+	}
+
+	if subdomainPattern.MatchString(strings.ToLower(raw)) {
+		name := strings.ToLower(raw)
+		return Account{Name: name, BaseURL: BaseURLForDomain(name)}, nil
+	}
+
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Account{}, fmt.Errorf("%q is not a subdomain, host or URL: %v", input, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Account{}, fmt.Errorf("%q: the scheme must be http or https", input)
+	}
+	if err := validateHost(u); err != nil {
+		return Account{}, fmt.Errorf("%q: %v", input, err)
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return Account{}, fmt.Errorf("%q: the API root can't have credentials, a query or a fragment", input)
+	}
+
+	host := strings.ToLower(u.Host)
+	if strings.HasSuffix(host, mocoHostSuffix) {
+		// Also undoes the suffix being added twice, as older versions did
+		sub := host
+		for strings.HasSuffix(sub, mocoHostSuffix) {
+			sub = strings.TrimSuffix(sub, mocoHostSuffix)
+		}
+		if subdomainPattern.MatchString(sub) {
+			return Account{Name: sub, BaseURL: BaseURLForDomain(sub)}, nil
+		}
+	}
+
+	root := &url.URL{Scheme: u.Scheme, Host: host, Path: u.Path}
+	if root.Path == "" || root.Path == "/" {
+		root.Path = "/api/v1/"
+	}
+	if !strings.HasSuffix(root.Path, "/") {
+		root.Path += "/"
+	}
+	return Account{Name: host, BaseURL: root.String()}, nil
+}
+
+// validateHost checks the host name or IP address and the port of u
+func validateHost(u *url.URL) error {
+	hostname := strings.ToLower(u.Hostname())
+	if hostname == "" {
+		return fmt.Errorf("missing host")
+	}
+	if net.ParseIP(hostname) == nil {
+		for _, label := range strings.Split(hostname, ".") {
+			if !subdomainPattern.MatchString(label) {
+				return fmt.Errorf("invalid host %q", hostname)
+			}
+		}
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid port %q", port)
+		}
+	}
+	return nil
+}
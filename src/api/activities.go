@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// activityRequest is the body accepted by POST and PUT activities
+type activityRequest struct {
+	Date        string  `json:"date"`
+	Hours       float64 `json:"hours"`
+	ProjectID   int     `json:"project_id"`
+	TaskID      int     `json:"task_id"`
+	Description string  `json:"description"`
+	Billable    *bool   `json:"billable,omitempty"`
+	Tag         string  `json:"tag,omitempty"`
+
+	RemoteService string `json:"remote_service,omitempty"`
+	RemoteID      string `json:"remote_id,omitempty"`
+	RemoteURL     string `json:"remote_url,omitempty"`
+}
+
+func newActivityRequest(entry types.TimeEntry) activityRequest {
+	req := activityRequest{
+		Date:        entry.Date,
+		Hours:       entry.Hours,
+		ProjectID:   entry.ProjectID,
+		TaskID:      entry.TaskID,
+		Description: entry.Description,
+		Billable:    entry.Billable,
+		Tag:         entry.Tag,
+
+		RemoteService: entry.RemoteService,
+		RemoteID:      entry.RemoteID,
+		RemoteURL:     entry.RemoteURL,
+	}
+	// Entries read from the API carry project and task as nested objects
+	if req.ProjectID == 0 {
+		req.ProjectID = entry.Project.ID
+	}
+	if req.TaskID == 0 {
+		req.TaskID = entry.Task.ID
+	}
+	return req
+}
+
+// activityUpdate is the body of a PUT. The server keeps fields that are
+// left out, so tag and ticket are always sent to let an edit clear them.
+type activityUpdate struct {
+	activityRequest
+	Tag string `json:"tag"`
+
+	RemoteService string `json:"remote_service"`
+	RemoteID      string `json:"remote_id"`
+	RemoteURL     string `json:"remote_url"`
+}
+
+func newActivityUpdate(entry types.TimeEntry) activityUpdate {
+	req := newActivityRequest(entry)
+	return activityUpdate{
+		activityRequest: req,
+		Tag:             req.Tag,
+		RemoteService:   req.RemoteService,
+		RemoteID:        req.RemoteID,
+		RemoteURL:       req.RemoteURL,
+	}
+}
+
+// Activities returns the time entries between from and to (YYYY-MM-DD, inclusive),
+// following all result pages
+func (c *Client) Activities(ctx context.Context, from, to string) ([]types.TimeEntry, ListInfo, error) {
+	query := url.Values{}
+	query.Set("from", from)
+	query.Set("to", to)
+
+	return getAll[types.TimeEntry](ctx, c, "activities?"+query.Encode())
+}
+
+// CreateActivity books a new time entry and returns it as stored by the server.
+//
+// POST is not idempotent, so when a create fails in a way that leaves its
+// outcome unknown (lost connection, 5xx) the client looks for the entry on
+// the server before sending it again, see FindCreatedActivity. Nothing is
+// looked up before the first attempt, which is all most creates need.
+func (c *Client) CreateActivity(ctx context.Context, entry types.TimeEntry) (*types.TimeEntry, error) {
+	jsonData, err := json.Marshal(newActivityRequest(entry))
+	if err != nil {
+		c.logger.LogError(err)
+		return nil, err
+	}
+
+	// Before an attempt with unknown outcome is repeated, look whether it
+	// was stored after all
+	sentAt := time.Now()
+	var dup *types.TimeEntry
+	resp, err := c.sendChecked(ctx, "POST", "activities", jsonData, nil, func(ctx context.Context) (bool, error) {
+		var err error
+		dup, err = c.FindCreatedActivity(ctx, entry, sentAt)
+		return dup != nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dup != nil {
+		return dup, nil
+	}
+
+	var created types.TimeEntry
+	if err := json.Unmarshal(resp.Body, &created); err != nil {
+		c.logger.LogError(err)
+		return nil, fmt.Errorf("error unmarshaling time entry: %v", err)
+	}
+	return &created, nil
+}
+
+// createdAtSlack allows for created_at and the Date header, from which the
+// server's clock is known, having whole seconds only
+const createdAtSlack = 2 * time.Second
+
+// FindCreatedActivity returns a stored entry like entry that the server
+// created at sentAt or later, or nil if there is none. sentAt is the local
+// time before entry was first sent. Entries like it that were booked before
+// are ignored, so booking the same thing twice on purpose still works.
+func (c *Client) FindCreatedActivity(ctx context.Context, entry types.TimeEntry, sentAt time.Time) (*types.TimeEntry, error) {
+	entries, _, err := c.Activities(ctx, entry.Date, entry.Date)
+	if err != nil {
+		return nil, err
+	}
+	watermark := c.serverTime(sentAt).Add(-createdAtSlack)
+	for i, e := range entries {
+		if SameActivity(e, entry) && e.CreatedAt != nil && !e.CreatedAt.Before(watermark) {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// SameActivity compares a stored entry with one that was submitted. Stored
+// entries carry the task as a nested object rather than task_id.
+func SameActivity(stored, submitted types.TimeEntry) bool {
+	taskID := stored.TaskID
+	if taskID == 0 {
+		taskID = stored.Task.ID
+	}
+	return stored.Date == submitted.Date &&
+		taskID == submitted.TaskID &&
+		math.Abs(stored.Hours-submitted.Hours) < 0.005 &&
+		stored.Description == submitted.Description
+}
+
+// UpdateActivity replaces the editable fields of the time entry with the given ID
+func (c *Client) UpdateActivity(ctx context.Context, id int, entry types.TimeEntry) (*types.TimeEntry, error) {
+	jsonData, err := json.Marshal(newActivityUpdate(entry))
+	if err != nil {
+		c.logger.LogError(err)
+		return nil, err
+	}
+
+	body, err := c.do(ctx, "PUT", fmt.Sprintf("activities/%d", id), jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated types.TimeEntry
+	if err := json.Unmarshal(body, &updated); err != nil {
+		c.logger.LogError(err)
+		return nil, fmt.Errorf("error unmarshaling time entry: %v", err)
+	}
+
+	return &updated, nil
+}
+
+// StartTimer starts the server-side timer of a time entry. Moco stops any
+// other running timer of the user.
+func (c *Client) StartTimer(ctx context.Context, id int) (*types.TimeEntry, error) {
+	return c.patchActivity(ctx, fmt.Sprintf("activities/%d/start_timer", id))
+}
+
+// StartNewTimer creates entry and starts its timer. If the timer can't be
+// started, the new entry is deleted again rather than left booked with 0h;
+// should that fail too, an *OrphanedEntryError names the entry.
+func (c *Client) StartNewTimer(ctx context.Context, entry types.TimeEntry) (*types.TimeEntry, error) {
+	created, err := c.CreateActivity(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	started, err := c.StartTimer(ctx, created.ID)
+	if err == nil {
+		return started, nil
+	}
+
+	// Clean up even if ctx ran out, which may be why starting failed
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	if deleteErr := c.DeleteActivity(cleanupCtx, created.ID); deleteErr != nil {
+		c.logger.LogError(deleteErr)
+		return nil, &OrphanedEntryError{Entry: *created, Err: err}
+	}
+	return nil, err
+}
+
+// OrphanedEntryError is returned by StartNewTimer when the timer of the new
+// entry couldn't be started and the entry couldn't be deleted either
+type OrphanedEntryError struct {
+	Entry types.TimeEntry // The entry left behind
+	Err   error           // Why the timer didn't start
+}
+
+func (e *OrphanedEntryError) Error() string {
+	return fmt.Sprintf("%v (entry #%d on %s was left booked with 0h)", e.Err, e.Entry.ID, e.Entry.Date)
+}
+
+func (e *OrphanedEntryError) Unwrap() error {
+	return e.Err
+}
+
+// StopTimer stops the timer of a time entry and adds the elapsed time to its hours
+func (c *Client) StopTimer(ctx context.Context, id int) (*types.TimeEntry, error) {
+	return c.patchActivity(ctx, fmt.Sprintf("activities/%d/stop_timer", id))
+}
+
+func (c *Client) patchActivity(ctx context.Context, path string) (*types.TimeEntry, error) {
+	body, err := c.do(ctx, "PATCH", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry types.TimeEntry
+	if err := json.Unmarshal(body, &entry); err != nil {
+		c.logger.LogError(err)
+		return nil, fmt.Errorf("error unmarshaling time entry: %v", err)
+	}
+
+	return &entry, nil
+}
+
+// RunningTimer returns the entry with a running timer within from and to, or nil
+func (c *Client) RunningTimer(ctx context.Context, from, to string) (*types.TimeEntry, error) {
+	entries, _, err := c.Activities(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return FindRunningTimer(entries), nil
+}
+
+// FindRunningTimer returns the first entry with a running timer, or nil
+func FindRunningTimer(entries []types.TimeEntry) *types.TimeEntry {
+	for i := range entries {
+		if entries[i].TimerStartedAt != nil {
+			entry := entries[i]
+			return &entry
+		}
+	}
+	return nil
+}
+
+// DeleteActivity removes the time entry with the given ID
+func (c *Client) DeleteActivity(ctx context.Context, id int) error {
+	_, err := c.do(ctx, "DELETE", fmt.Sprintf("activities/%d", id), nil)
+	return err
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/denwerk/moco/src/mocotest"
+	"github.com/denwerk/moco/src/types"
+)
+
+// newTestClient returns a client for srv that retries without waiting
+func newTestClient(t *testing.T, srv *mocotest.Server, opts ...Option) *Client {
+	t.Helper()
+	opts = append([]Option{
+		WithBaseURL(srv.URL()),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}),
+	}, opts...)
+	c, err := NewClient("", srv.APIKey(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestStartNewTimer(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	c := newTestClient(t, srv)
+
+	today := time.Now().Format("2006-01-02")
+	started, err := c.StartNewTimer(context.Background(), types.TimeEntry{Date: today, ProjectID: 1000, TaskID: 2000, Description: "Timer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.TimerStartedAt == nil {
+		t.Error("timer not running")
+	}
+	if n := len(srv.Activities()); n != 1 {
+		t.Errorf("%d activities stored, want 1", n)
+	}
+}
+
+func TestStartNewTimerDeletesEntryWhenTimerFails(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	c := newTestClient(t, srv)
+
+	// The fake only starts timers on today's entries
+	_, err := c.StartNewTimer(context.Background(), types.TimeEntry{Date: "2020-01-01", ProjectID: 1000, TaskID: 2000, Description: "Timer"})
+	if err == nil {
+		t.Fatal("StartNewTimer succeeded")
+	}
+	if entries := srv.Activities(); len(entries) != 0 {
+		t.Errorf("entries left behind: %+v", entries)
+	}
+}
+
+func TestUpdateActivityClearsTagAndTicket(t *testing.T) {
+	stored := types.TimeEntry{ID: 1, Date: "2026-10-12", Hours: 1, ProjectID: 1000, TaskID: 2000, Description: "Fix",
+		Tag: "bug", RemoteService: "jira", RemoteID: "ACME-42", RemoteURL: "https://jira.example.com/browse/ACME-42"}
+	srv := mocotest.NewServer(mocotest.WithActivities([]types.TimeEntry{stored}))
+	defer srv.Close()
+	c := newTestClient(t, srv)
+
+	edited := stored
+	edited.Tag, edited.RemoteService, edited.RemoteID, edited.RemoteURL = "", "", "", ""
+	if _, err := c.UpdateActivity(context.Background(), stored.ID, edited); err != nil {
+		t.Fatal(err)
+	}
+	got := srv.Activities()[0]
+	if got.Tag != "" || got.RemoteService != "" || got.RemoteID != "" || got.RemoteURL != "" {
+		t.Errorf("stored tag %q, ticket %q %q %q, want them cleared", got.Tag, got.RemoteService, got.RemoteID, got.RemoteURL)
+	}
+}
@@ -0,0 +1,289 @@
+// Package api implements a client for the Moco REST API.
+//
+// It has no dependency on the TUI so it can be used from other tools.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultUserAgent is sent when no user agent is configured
+const DefaultUserAgent = "moco-tui"
+
+// DefaultTimeout is used for the HTTP client when none is injected
+const DefaultTimeout = 30 * time.Second
+
+// DefaultMaxPages limits how many pages a single list call follows
+const DefaultMaxPages = 50
+
+// Logger receives request and response traces from the client
+type Logger interface {
+	LogRequest(method, url string, body []byte)
+	LogResponse(statusCode int, body []byte)
+	LogError(err error)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) LogRequest(string, string, []byte) {}
+func (nopLogger) LogResponse(int, []byte)           {}
+func (nopLogger) LogError(error)                    {}
+
+// Client talks to a single Moco account
+type Client struct {
+	baseURL    *url.URL
+	apiKey     string
+	httpClient *http.Client
+	userAgent  string
+	logger     Logger
+	maxPages   int
+	retry      RetryPolicy
+	onRetry    func(RetryEvent)
+
+	// clockOffset is how far the server's clock is ahead of ours, in
+	// nanoseconds, as seen in the Date header of the last response
+	clockOffset atomic.Int64
+}
+
+// Option configures a Client
+type Option func(*Client) error
+
+// WithHTTPClient replaces the default HTTP client, e.g. to inject a transport
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) error {
+		if hc == nil {
+			return fmt.Errorf("http client must not be nil")
+		}
+		c.httpClient = hc
+		return nil
+	}
+}
+
+// WithBaseURL points the client at a different API root, e.g. a local stand-in server
+func WithBaseURL(rawURL string) Option {
+	return func(c *Client) error {
+		u, err := parseBaseURL(rawURL)
+		if err != nil {
+			return err
+		}
+		c.baseURL = u
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) error {
+		c.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithMaxPages limits how many pages a list call follows before it gives up
+// and reports the result as truncated
+func WithMaxPages(n int) Option {
+	return func(c *Client) error {
+		if n < 1 {
+			return fmt.Errorf("max pages must be at least 1")
+		}
+		c.maxPages = n
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to trace requests
+func WithLogger(logger Logger) Option {
+	return func(c *Client) error {
+		if logger == nil {
+			logger = nopLogger{}
+		}
+		c.logger = logger
+		return nil
+	}
+}
+
+// BaseURLForDomain returns the API root for a mocoapp.com subdomain
+func BaseURLForDomain(domain string) string {
+	return fmt.Sprintf("https://%s.mocoapp.com/api/v1/", domain)
+}
+
+// NewClient creates a client for the given account. Unless WithBaseURL is
+// given, requests go to the mocoapp.com subdomain named by domain.
+func NewClient(domain, apiKey string, opts ...Option) (*Client, error) {
+	c := &Client{
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		userAgent:  DefaultUserAgent,
+		logger:     nopLogger{},
+		maxPages:   DefaultMaxPages,
+		retry:      DefaultRetryPolicy,
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.baseURL == nil {
+		if domain == "" {
+			return nil, fmt.Errorf("either a domain or a base URL is required")
+		}
+		u, err := parseBaseURL(BaseURLForDomain(domain))
+		if err != nil {
+			return nil, err
+		}
+		c.baseURL = u
+	}
+
+	return c, nil
+}
+
+// serverTime converts a local time to the server's clock
+func (c *Client) serverTime(t time.Time) time.Time {
+	return t.Add(time.Duration(c.clockOffset.Load()))
+}
+
+// BaseURL returns the API root the client sends requests to
+func (c *Client) BaseURL() string {
+	return c.baseURL.String()
+}
+
+func parseBaseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %v", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", rawURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: missing host", rawURL)
+	}
+	// Relative paths are resolved against the base, so it must end in a slash
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
+
+// Error is returned when the API answers with a status >= 400
+type Error struct {
+	StatusCode int
+	Message    string
+	Body       []byte
+	RetryAfter time.Duration // Parsed Retry-After header, 0 if absent
+}
+
+// IsRateLimited reports whether the server rejected the request with 429
+func (e *Error) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsTemporary reports whether repeating the request later may succeed
+func (e *Error) IsTemporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, string(e.Body))
+}
+
+type response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	resp, err := c.send(ctx, method, path, body, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) sendOnce(ctx context.Context, method, path string, body []byte, header http.Header) (*response, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	reqURL := c.baseURL.ResolveReference(ref).String()
+	c.logger.LogRequest(method, reqURL, body)
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, bodyReader)
+	if err != nil {
+		c.logger.LogError(err)
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Token "+c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.LogError(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.logger.LogError(err)
+		return nil, err
+	}
+
+	c.logger.LogResponse(resp.StatusCode, responseBody)
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		c.clockOffset.Store(int64(date.Sub(time.Now())))
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &Error{
+			StatusCode: resp.StatusCode,
+			Body:       responseBody,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+		// Try to parse error message from response
+		var errorResponse struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(responseBody, &errorResponse); err == nil {
+			apiErr.Message = errorResponse.Error
+		}
+		return nil, apiErr
+	}
+
+	return &response{StatusCode: resp.StatusCode, Header: resp.Header, Body: responseBody}, nil
+}
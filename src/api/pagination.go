@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ListInfo describes how complete a paginated list is
+type ListInfo struct {
+	Total     int  // Total reported by the server via X-Total, 0 if unknown
+	Fetched   int  // Number of items actually returned
+	Pages     int  // Number of pages requested
+	Truncated bool // True if items were left out because of the page limit
+}
+
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+// nextLink extracts the rel="next" target from a Link header
+func nextLink(header string) string {
+	if m := nextLinkPattern.FindStringSubmatch(header); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// nextPage returns the path and query of the rel="next" target of a Link
+// header, or "" if there is none. Requests carry the API key, so a target
+// on another host or scheme is refused rather than followed.
+func (c *Client) nextPage(header string) (string, error) {
+	link := nextLink(header)
+	if link == "" {
+		return "", nil
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid next page link %q: %v", link, err)
+	}
+	target := c.baseURL.ResolveReference(ref)
+	if target.Scheme != c.baseURL.Scheme || !strings.EqualFold(target.Host, c.baseURL.Host) {
+		return "", fmt.Errorf("next page link points to %s://%s, not to the API at %s://%s",
+			target.Scheme, target.Host, c.baseURL.Scheme, c.baseURL.Host)
+	}
+	return (&url.URL{Path: target.Path, RawPath: target.RawPath, RawQuery: target.RawQuery}).String(), nil
+}
+
+// getAll follows Moco's pagination and collects every page of a list endpoint
+func getAll[T any](ctx context.Context, c *Client, path string) ([]T, ListInfo, error) {
+	items, info, _, err := getAllConditional[T](ctx, c, path, Validators{})
+	return items, info, err
+}
+
+// getAllConditional is getAll with cache validators for the first page. It
+// returns ErrNotModified if the server reports the cached copy as current,
+// and the validators of the first page otherwise.
+func getAllConditional[T any](ctx context.Context, c *Client, path string, validators Validators) ([]T, ListInfo, Validators, error) {
+	var (
+		items []T
+		info  ListInfo
+		fresh Validators
+	)
+
+	next := path
+	for page := 1; next != ""; page++ {
+		if info.Pages >= c.maxPages {
+			info.Truncated = true
+			break
+		}
+
+		var header http.Header
+		if page == 1 {
+			header = validators.header()
+		}
+		resp, err := c.send(ctx, "GET", next, nil, header)
+		if err != nil {
+			return nil, info, fresh, err
+		}
+		info.Pages++
+
+		if page == 1 {
+			if resp.StatusCode == http.StatusNotModified {
+				return nil, info, validators, ErrNotModified
+			}
+			fresh = Validators{
+				ETag:         resp.Header.Get("ETag"),
+				LastModified: resp.Header.Get("Last-Modified"),
+			}
+		}
+
+		var pageItems []T
+		if err := json.Unmarshal(resp.Body, &pageItems); err != nil {
+			c.logger.LogError(err)
+			return nil, info, fresh, fmt.Errorf("error unmarshaling page %d of %s: %v", page, path, err)
+		}
+		items = append(items, pageItems...)
+
+		if total, err := strconv.Atoi(resp.Header.Get("X-Total")); err == nil {
+			info.Total = total
+		}
+
+		next, err = c.nextPage(resp.Header.Get("Link"))
+		if err != nil {
+			return nil, info, fresh, err
+		}
+		if next == "" && info.Total > len(items) && len(pageItems) > 0 {
+			// No Link header, but the server says there is more
+			next = withPage(path, page+1)
+		}
+	}
+
+	info.Fetched = len(items)
+	if info.Total > info.Fetched {
+		info.Truncated = true
+	}
+
+	return items, info, fresh, nil
+}
+
+// Validators identify a previously fetched response for conditional requests
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ErrNotModified is returned by conditional requests when the cached copy is current
+var ErrNotModified = errors.New("not modified")
+
+func (v Validators) header() http.Header {
+	header := http.Header{}
+	if v.ETag != "" {
+		header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		header.Set("If-Modified-Since", v.LastModified)
+	}
+	return header
+}
+
+// withPage sets the page query parameter on a relative path
+func withPage(path string, page int) string {
+	u, err := url.Parse(path)
+	if err != nil {
+		return ""
+	}
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
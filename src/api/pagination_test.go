@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/denwerk/moco/src/mocotest"
+)
+
+func TestActivitiesFollowsPages(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithPerPage(3))
+	defer srv.Close()
+	c := newTestClient(t, srv)
+
+	all := srv.Activities()
+	entries, info, err := c.Activities(context.Background(), "2000-01-01", "2100-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(all) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(all))
+	}
+	wantPages := (len(all) + 2) / 3
+	if info.Total != len(all) || info.Fetched != len(all) || info.Pages != wantPages || info.Truncated {
+		t.Errorf("info = %+v, want %d of %d in %d pages", info, len(all), len(all), wantPages)
+	}
+	seen := map[int]bool{}
+	for _, e := range entries {
+		if seen[e.ID] {
+			t.Errorf("entry %d returned twice", e.ID)
+		}
+		seen[e.ID] = true
+	}
+}
+
+func TestActivitiesStopsAtMaxPages(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithPerPage(3))
+	defer srv.Close()
+	c := newTestClient(t, srv, WithMaxPages(2))
+
+	entries, info, err := c.Activities(context.Background(), "2000-01-01", "2100-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 || info.Pages != 2 || !info.Truncated || info.Total != len(srv.Activities()) {
+		t.Errorf("got %d entries, info %+v, want 6 entries from 2 pages, truncated", len(entries), info)
+	}
+}
+
+func TestAssignedProjectsFollowsPages(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithPerPage(1))
+	defer srv.Close()
+	c := newTestClient(t, srv)
+
+	projects, info, err := c.AssignedProjects(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(mocotest.SeedProjects()); len(projects) != want || info.Pages != want {
+		t.Errorf("got %d projects in %d pages, want %d", len(projects), info.Pages, want)
+	}
+}
+
+func TestActivitiesWithoutLinkHeader(t *testing.T) {
+	// Pages are requested by number when only X-Total says there is more
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Total", "3")
+		switch r.URL.Query().Get("page") {
+		case "", "1":
+			fmt.Fprint(w, `[{"id":1},{"id":2}]`)
+		case "2":
+			fmt.Fprint(w, `[{"id":3}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer srv.Close()
+	c, err := NewClient("", "key", WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, info, err := c.Activities(context.Background(), "2026-10-01", "2026-10-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || info.Pages != 2 || info.Truncated {
+		t.Errorf("got %d entries, info %+v, want 3 entries from 2 pages", len(entries), info)
+	}
+}
+
+func TestActivitiesRefusesForeignNextLink(t *testing.T) {
+	var leaked []string
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = append(leaked, r.Header.Get("Authorization"))
+		fmt.Fprint(w, `[]`)
+	}))
+	defer foreign.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/api/v1/activities?page=2>; rel="next"`, foreign.URL))
+		fmt.Fprint(w, `[{"id":1}]`)
+	}))
+	defer srv.Close()
+	c, err := NewClient("", "secret", WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := c.Activities(context.Background(), "2026-10-01", "2026-10-31"); err == nil {
+		t.Error("followed a next link to another host")
+	}
+	if len(leaked) > 0 {
+		t.Errorf("foreign host got %d requests, with %q", len(leaked), leaked)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	c, err := NewClient("", "key", WithBaseURL("https://acme.mocoapp.com/api/v1/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		link string
+		want string
+		ok   bool
+	}{
+		{"https://acme.mocoapp.com/api/v1/activities?page=2", "/api/v1/activities?page=2", true},
+		{"https://ACME.mocoapp.com/api/v1/projects?page=3&per_page=50", "/api/v1/projects?page=3&per_page=50", true},
+		{"activities?page=2", "/api/v1/activities?page=2", true},
+		{"http://acme.mocoapp.com/api/v1/activities?page=2", "", false},
+		{"https://evil.example.com/api/v1/activities?page=2", "", false},
+		{"//evil.example.com/api/v1/activities?page=2", "", false},
+		{"https://acme.mocoapp.com:8443/api/v1/activities?page=2", "", false},
+	}
+	for _, tt := range tests {
+		got, err := c.nextPage(`<` + tt.link + `>; rel="next"`)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("nextPage(%q) = %q, %v, want %q", tt.link, got, err, tt.want)
+		}
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := map[string]string{
+		`<https://x.mocoapp.com/api/v1/activities?page=2>; rel="next"`:                                                     "https://x.mocoapp.com/api/v1/activities?page=2",
+		`<https://x/api/v1/a?page=1>; rel="prev", <https://x/api/v1/a?page=3>; rel="next", <https://x/a?page=9>; rel=last`: "https://x/api/v1/a?page=3",
+		`<https://x/api/v1/a?page=1>; rel="prev"`:                                                                          "",
+		"": "",
+	}
+	for header, want := range tests {
+		if got := nextLink(header); got != want {
+			t.Errorf("nextLink(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestWithPage(t *testing.T) {
+	if got := withPage("activities?from=2026-10-01&to=2026-10-31", 3); got != "activities?from=2026-10-01&page=3&to=2026-10-31" {
+		t.Errorf("withPage = %q", got)
+	}
+}
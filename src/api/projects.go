@@ -0,0 +1,19 @@
+package api
+
+import (
+	"context"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// AssignedProjects returns the projects and tasks the user can book on,
+// following all result pages
+func (c *Client) AssignedProjects(ctx context.Context) ([]types.Project, ListInfo, error) {
+	return getAll[types.Project](ctx, c, "projects/assigned")
+}
+
+// AssignedProjectsIfChanged is AssignedProjects as a conditional request.
+// It returns ErrNotModified if the list matching validators is still current.
+func (c *Client) AssignedProjectsIfChanged(ctx context.Context, validators Validators) ([]types.Project, ListInfo, Validators, error) {
+	return getAllConditional[types.Project](ctx, c, "projects/assigned", validators)
+}
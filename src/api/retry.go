@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are repeated
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first, 1 disables retries
+	BaseDelay   time.Duration // Delay before the second attempt, doubled for each further one
+	MaxDelay    time.Duration // Upper bound for a single delay, including Retry-After
+}
+
+// DefaultRetryPolicy is used unless WithRetryPolicy is given
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// RetryEvent is passed to the retry hook before the client waits for the next attempt
+type RetryEvent struct {
+	Method  string
+	Path    string
+	Attempt int           // The attempt that just failed
+	Delay   time.Duration // How long the client waits before trying again
+	Err     error
+}
+
+func (e RetryEvent) String() string {
+	delay := e.Delay.Round(time.Second)
+	var apiErr *Error
+	if errors.As(e.Err, &apiErr) {
+		if apiErr.IsRateLimited() {
+			return fmt.Sprintf("rate limited, retrying in %s", delay)
+		}
+		return fmt.Sprintf("server error %d, retrying in %s", apiErr.StatusCode, delay)
+	}
+	return fmt.Sprintf("network error, retrying in %s", delay)
+}
+
+// WithRetryPolicy replaces the default retry policy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) error {
+		if policy.MaxAttempts < 1 {
+			return fmt.Errorf("retry policy needs at least one attempt")
+		}
+		c.retry = policy
+		return nil
+	}
+}
+
+// WithRetryNotify registers a hook that is called before every retry
+func WithRetryNotify(fn func(RetryEvent)) Option {
+	return func(c *Client) error {
+		c.onRetry = fn
+		return nil
+	}
+}
+
+// send performs a request and retries it according to the retry policy.
+// Requests that are not idempotent are only repeated when the server
+// rejected them outright (429) or never got them, so they can never be
+// applied twice.
+func (c *Client) send(ctx context.Context, method, path string, body []byte, header http.Header) (*response, error) {
+	return c.sendChecked(ctx, method, path, body, header, nil)
+}
+
+// sendChecked is send for a request that is not idempotent but whose effect
+// can be looked up. After an attempt with unknown outcome, applied is asked
+// whether the request went through before it is sent again; if so,
+// sendChecked returns neither a response nor an error. If the lookup fails
+// the request isn't repeated. All attempts count against MaxAttempts.
+func (c *Client) sendChecked(ctx context.Context, method, path string, body []byte, header http.Header, applied func(context.Context) (bool, error)) (*response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.sendOnce(ctx, method, path, body, header)
+		if err == nil {
+			return resp, nil
+		}
+
+		checkFirst := !isIdempotent(method) && isAmbiguous(err)
+		retryable := isRejected(err) || isUnsent(err) || (isAmbiguous(err) && (!checkFirst || applied != nil))
+		if !retryable || attempt >= c.retry.MaxAttempts {
+			return nil, err
+		}
+
+		delay, ok := c.retry.delay(attempt, err)
+		if !ok {
+			return nil, err
+		}
+		if err := c.wait(ctx, RetryEvent{Method: method, Path: path, Attempt: attempt, Delay: delay, Err: err}); err != nil {
+			return nil, err
+		}
+
+		if checkFirst {
+			done, lookupErr := applied(ctx)
+			if lookupErr != nil {
+				return nil, err
+			}
+			if done {
+				return nil, nil
+			}
+		}
+	}
+}
+
+// wait reports a retry and sleeps until the next attempt or the context ends
+func (c *Client) wait(ctx context.Context, event RetryEvent) error {
+	c.logger.LogError(fmt.Errorf("%s %s attempt %d failed: %v (%s)", event.Method, event.Path, event.Attempt, event.Err, event))
+	if c.onRetry != nil {
+		c.onRetry(event)
+	}
+
+	timer := time.NewTimer(event.Delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// delay returns how long to wait after the given attempt failed. It reports
+// false if the server asked for a longer pause than MaxDelay allows.
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		if p.MaxDelay > 0 && apiErr.RetryAfter > p.MaxDelay {
+			return 0, false
+		}
+		return apiErr.RetryAfter, true
+	}
+
+	delay := p.BaseDelay << (attempt - 1)
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	// Add up to 20% jitter so parallel clients don't retry in lockstep
+	if delay > 0 {
+		delay += time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	}
+	return delay, true
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// isRejected reports whether the server refused the request without processing it
+func isRejected(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.IsRateLimited()
+}
+
+// isUnsent reports whether a request failed before it reached the server:
+// the host name didn't resolve or no connection could be opened
+func isUnsent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isAmbiguous reports whether a request may or may not have been applied
+// and repeating it could succeed: server errors and network failures after
+// the request was sent
+func isAmbiguous(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || isUnsent(err) {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.IsTemporary() && !apiErr.IsRateLimited()
+	}
+	// Anything else comes from the transport
+	return true
+}
+
+// MayHaveBeenApplied reports whether a failed write may have been applied
+// by the server all the same: the request went out, but no answer came
+// back that refused it
+func MayHaveBeenApplied(err error) bool {
+	if err == nil || isUnsent(err) || isRejected(err) {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.IsTemporary()
+	}
+	// Lost connections and timeouts leave it open
+	return true
+}
+
+// parseRetryAfter understands both delay-seconds and HTTP-date values
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/denwerk/moco/src/mocotest"
+	"github.com/denwerk/moco/src/types"
+)
+
+var testEntry = types.TimeEntry{Date: "2024-03-04", Hours: 1.5, ProjectID: 1000, TaskID: 2000, Description: "Retry"}
+
+func TestRetriesRateLimitedRequests(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	srv.Fail(mocotest.Fault{Method: http.MethodPost, Path: "activities", Status: http.StatusTooManyRequests, RetryAfter: "0"})
+	c := newTestClient(t, srv)
+
+	if _, err := c.CreateActivity(context.Background(), testEntry); err != nil {
+		t.Fatal(err)
+	}
+	// A rejected create is sent again without looking for it first
+	want := []string{"POST activities", "POST activities"}
+	if got := srv.Requests(); !reflect.DeepEqual(got, want) {
+		t.Errorf("requests = %q, want %q", got, want)
+	}
+	if n := len(srv.Activities()); n != 1 {
+		t.Errorf("%d activities stored, want 1", n)
+	}
+}
+
+func TestRetriesServerErrorsOfReads(t *testing.T) {
+	srv := mocotest.NewServer()
+	defer srv.Close()
+	srv.Fail(mocotest.Fault{Method: http.MethodGet, Path: "activities", Status: http.StatusServiceUnavailable, Count: 2})
+	var events []RetryEvent
+	c := newTestClient(t, srv, WithRetryNotify(func(e RetryEvent) { events = append(events, e) }))
+
+	if _, _, err := c.Activities(context.Background(), "2000-01-01", "2100-01-01"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Attempt != 1 || events[1].Attempt != 2 {
+		t.Errorf("retry events = %+v, want attempts 1 and 2", events)
+	}
+}
+
+func TestGivesUpWhenRetryAfterExceedsMaxDelay(t *testing.T) {
+	srv := mocotest.NewServer()
+	defer srv.Close()
+	srv.Fail(mocotest.Fault{Method: http.MethodGet, Path: "activities", Status: http.StatusTooManyRequests, RetryAfter: "120"})
+	c := newTestClient(t, srv)
+
+	_, _, err := c.Activities(context.Background(), "2000-01-01", "2100-01-01")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !apiErr.IsRateLimited() || apiErr.RetryAfter != 2*time.Minute {
+		t.Fatalf("err = %v, want rate limited with Retry-After 2m", err)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("%d requests, want 1", n)
+	}
+}
+
+func TestCreateActivitySendsOnlyThePost(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	c := newTestClient(t, srv)
+
+	created, err := c.CreateActivity(context.Background(), testEntry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.CreatedAt == nil {
+		t.Errorf("created = %+v, want ID and created_at", created)
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, []string{"POST activities"}) {
+		t.Errorf("requests = %q, want only the POST", got)
+	}
+}
+
+func TestCreateActivityFindsEntryAfterLostResponse(t *testing.T) {
+	// The server clock being off must not matter, created_at is compared
+	// with the time on the server
+	for _, skew := range []time.Duration{0, time.Hour, -time.Hour} {
+		srv := mocotest.NewServer(mocotest.WithActivities(nil), mocotest.WithClock(func() time.Time { return time.Now().Add(skew) }))
+		srv.Fail(mocotest.Fault{Method: http.MethodPost, Path: "activities", Apply: true})
+		c := newTestClient(t, srv)
+
+		created, err := c.CreateActivity(context.Background(), testEntry)
+		if err != nil {
+			t.Fatalf("skew %s: %v", skew, err)
+		}
+		stored := srv.Activities()
+		if len(stored) != 1 || stored[0].ID != created.ID {
+			t.Errorf("skew %s: stored %+v, want only #%d", skew, stored, created.ID)
+		}
+		want := []string{"POST activities", "GET activities"}
+		if got := srv.Requests(); !reflect.DeepEqual(got, want) {
+			t.Errorf("skew %s: requests = %q, want %q", skew, got, want)
+		}
+		srv.Close()
+	}
+}
+
+func TestCreateActivityRetriesWhenNothingWasStored(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	srv.Fail(mocotest.Fault{Method: http.MethodPost, Path: "activities", Status: http.StatusBadGateway})
+	c := newTestClient(t, srv)
+
+	if _, err := c.CreateActivity(context.Background(), testEntry); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"POST activities", "GET activities", "POST activities"}
+	if got := srv.Requests(); !reflect.DeepEqual(got, want) {
+		t.Errorf("requests = %q, want %q", got, want)
+	}
+	if n := len(srv.Activities()); n != 1 {
+		t.Errorf("%d activities stored, want 1", n)
+	}
+}
+
+func TestCreateActivityBooksIntentionalDuplicate(t *testing.T) {
+	earlier := time.Now().Add(-time.Hour)
+	existing := testEntry
+	existing.ID = 1
+	existing.CreatedAt = &earlier
+	srv := mocotest.NewServer(mocotest.WithActivities([]types.TimeEntry{existing}))
+	defer srv.Close()
+	srv.Fail(mocotest.Fault{Method: http.MethodPost, Path: "activities", Status: http.StatusBadGateway})
+	c := newTestClient(t, srv)
+
+	created, err := c.CreateActivity(context.Background(), testEntry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == existing.ID {
+		t.Fatal("the earlier entry was taken for the new one")
+	}
+	if n := len(srv.Activities()); n != 2 {
+		t.Errorf("%d activities stored, want 2", n)
+	}
+}
+
+func TestCreateActivityKeepsErrorWhenLookupFails(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	srv.Fail(mocotest.Fault{Method: http.MethodPost, Path: "activities", Status: http.StatusBadGateway})
+	srv.Fail(mocotest.Fault{Method: http.MethodGet, Path: "activities", Status: http.StatusInternalServerError, Count: 3})
+	c := newTestClient(t, srv)
+
+	_, err := c.CreateActivity(context.Background(), testEntry)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("err = %v, want the 502 of the create", err)
+	}
+	// Without knowing whether the first attempt was stored, it isn't sent again
+	for _, r := range srv.Requests()[1:] {
+		if r == "POST activities" {
+			t.Errorf("create sent again: %q", srv.Requests())
+		}
+	}
+}
+
+func TestCreateActivityAttemptsWithinMaxAttempts(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	srv.Fail(mocotest.Fault{Method: http.MethodPost, Path: "activities", Status: http.StatusBadGateway})
+	srv.Fail(mocotest.Fault{Method: http.MethodPost, Path: "activities", Status: http.StatusTooManyRequests, Count: 5})
+	c := newTestClient(t, srv)
+
+	_, err := c.CreateActivity(context.Background(), testEntry)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !apiErr.IsRateLimited() {
+		t.Fatalf("err = %v, want the last 429", err)
+	}
+	posts := 0
+	for _, r := range srv.Requests() {
+		if r == "POST activities" {
+			posts++
+		}
+	}
+	if posts != c.retry.MaxAttempts {
+		t.Errorf("create sent %d times, want at most MaxAttempts = %d: %q", posts, c.retry.MaxAttempts, srv.Requests())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-5", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, base := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		got, ok := p.delay(attempt, errors.New("connection reset"))
+		if !ok || got < base || got > base+base/5 {
+			t.Errorf("delay(%d) = %s, %v, want %s plus up to 20%%", attempt, got, ok, base)
+		}
+	}
+	if got, ok := p.delay(1, &Error{StatusCode: 429, RetryAfter: 3 * time.Second}); !ok || got != 3*time.Second {
+		t.Errorf("delay with Retry-After 3s = %s, %v", got, ok)
+	}
+	if _, ok := p.delay(1, &Error{StatusCode: 429, RetryAfter: time.Minute}); ok {
+		t.Error("Retry-After beyond MaxDelay accepted")
+	}
+}
+
+func TestMayHaveBeenApplied(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &Error{StatusCode: 502}, true},
+		{"rejected", &Error{StatusCode: 422}, false},
+		{"rate limited", &Error{StatusCode: 429}, false},
+		{"not connected", &url.Error{Op: "Post", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, false},
+		{"unknown host", &url.Error{Op: "Post", Err: &net.DNSError{Err: "no such host"}}, false},
+		{"connection lost", &url.Error{Op: "Post", Err: io.EOF}, true},
+		{"timeout", context.DeadlineExceeded, true},
+	}
+	for _, tt := range tests {
+		if got := MayHaveBeenApplied(tt.err); got != tt.want {
+			t.Errorf("%s: MayHaveBeenApplied = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// Session returns the user the API key belongs to. It is a cheap way to
+// check that the key is valid.
+func (c *Client) Session(ctx context.Context) (*types.Session, error) {
+	body, err := c.do(ctx, "GET", "session", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var session types.Session
+	if err := json.Unmarshal(body, &session); err != nil {
+		c.logger.LogError(err)
+		return nil, err
+	}
+	return &session, nil
+}
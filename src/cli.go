@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/denwerk/moco/src/api"
+)
+
+// Exit codes of the subcommands
+const (
+	exitOK          = 0
+	exitError       = 1 // The API rejected the request or something unexpected failed
+	exitUsage       = 2 // Invalid flags or input
+	exitConfig      = 3 // Missing or invalid configuration, or the API key was refused
+	exitUnavailable = 4 // Network trouble or a temporary server error; safe to retry
+)
+
+// command is a non-interactive subcommand
+type command struct {
+	name        string
+	summary     string
+	run         func(args []string) int
+	subcommands []string // Offered by shell completion, e.g. "start" for timer
+	hidden      bool     // Left out of the help, for commands used by scripts
+
+	// flags returns a new flag set of the command, or of its subcommand
+	// sub, without running anything. Shell completion reads the flag names
+	// from it.
+	flags func(sub string) *flag.FlagSet
+}
+
+// commands maps subcommand names to their implementation. It is filled in
+// by init functions next to each command.
+var commands = map[string]command{}
+
+func registerCommand(cmd command) {
+	commands[cmd.name] = cmd
+}
+
+// runCommand runs the subcommand named by args[0], if there is one
+func runCommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	if args[0] == "help" {
+		printCommands(os.Stdout)
+		return exitOK, true
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return 0, false
+	}
+	return cmd.run(args[1:]), true
+}
+
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "Usage: moco [--demo] [--profile <name>]   start the interactive TUI")
+	fmt.Fprintln(w, "       moco <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	var names []string
+	for name, cmd := range commands {
+		if !cmd.hidden {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-12s %s\n", name, commands[name].summary)
+	}
+}
+
+// newFlagSet creates the flag set of a subcommand with the flags all of
+// them share
+func newFlagSet(name, usage string) (*flag.FlagSet, *bool) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: moco %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	demo := fs.Bool("demo", false, "run against a built-in fake Moco account")
+	registerConfigFlags(fs)
+	return fs, demo
+}
+
+// parseFlags parses args and reports the exit code to use if that failed
+//
+// Flags may follow positional arguments, as in "moco ls today --format json";
+// the positional arguments are collected and available through fs.Args().
+// Everything after "--" is positional, even if it starts with a dash.
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return exitOK, false
+			}
+			return exitUsage, false
+		}
+		rest := fs.Args()
+		// Parse consumes a terminating "--" but stops in front of other
+		// positional arguments
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		args = rest
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	// Parse once more so fs.Args() reports the positional arguments
+	_ = fs.Parse(append([]string{"--"}, positional...))
+	return 0, true
+}
+
+// cliSession is the configuration and client a subcommand works with
+type cliSession struct {
+	cfg    *Config
+	client *api.Client
+	stop   func()
+}
+
+// openSession loads the configuration, or starts the demo server, and
+// creates an API client that doesn't log to the terminal
+func openSession(demo bool) (*cliSession, error) {
+	var (
+		cfg  *Config
+		stop = func() {}
+		err  error
+	)
+	if demo {
+		cfg, stop, err = startDemo()
+	} else {
+		cfg, err = LoadConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newClient(cfg, api.WithLogger(nil))
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	return &cliSession{cfg: cfg, client: client, stop: stop}, nil
+}
+
+func (s *cliSession) Close() {
+	s.stop()
+}
+
+// fail prints err to stderr and returns the matching exit code
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "moco: %s\n", describeError(err))
+	code := exitCodeFor(err)
+	if code == exitConfig {
+		fmt.Fprintln(os.Stderr, "Run 'moco doctor' to check your setup.")
+	}
+	return code
+}
+
+// usageError is returned for invalid input, which exits with exitUsage
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{fmt.Sprintf(format, args...)}
+}
+
+func exitCodeFor(err error) int {
+	var usageErr *usageError
+	var configErr *ConfigError
+	var apiErr *api.Error
+	switch {
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &configErr):
+		return exitConfig
+	case errors.As(err, &apiErr) && (apiErr.StatusCode == 401 || apiErr.StatusCode == 403):
+		return exitConfig
+	case shouldQueue(err):
+		return exitUnavailable
+	}
+	return exitError
+}
+
+// parseDate understands YYYY-MM-DD, "today", "yesterday" and weekday names,
+// which refer to the most recent such day
+func parseDate(input string, now time.Time) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(input))
+	switch value {
+	case "", "today":
+		return now.Format("2006-01-02"), nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1).Format("2006-01-02"), nil
+	}
+
+	for i := 1; i <= 7; i++ {
+		day := now.AddDate(0, 0, -i)
+		if strings.ToLower(day.Weekday().String()) == value {
+			return day.Format("2006-01-02"), nil
+		}
+	}
+
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		return "", usageErrorf("invalid date %q: use YYYY-MM-DD, today, yesterday or a weekday", input)
+	}
+	return value, nil
+}
+
+func cliContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 2*time.Minute)
+}
+
+// stdin is shared by all prompts, so input read ahead by one isn't lost
+// to the next
+var stdin = bufio.NewReader(os.Stdin)
+
+// prompt asks for a line of input on the terminal
+func prompt(question string) string {
+	fmt.Print(question)
+	answer, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(answer)
+}
+
+// confirm asks a yes/no question on the terminal
+func confirm(question string) bool {
+	answer := strings.ToLower(prompt(question + " [y/N] "))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		format     string
+		positional []string
+	}{
+		{"flags first", []string{"--format", "json", "today"}, "json", []string{"today"}},
+		{"interleaved", []string{"today", "--format", "json", "extra"}, "json", []string{"today", "extra"}},
+		{"double dash", []string{"--format", "csv", "--", "-x", "--format", "json"}, "csv", []string{"-x", "--format", "json"}},
+		{"double dash after positional", []string{"a", "--", "-b"}, "", []string{"a", "-b"}},
+		{"double dash only", []string{"--"}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			format := fs.String("format", "", "")
+			if _, ok := parseFlags(fs, tt.args); !ok {
+				t.Fatal("parseFlags failed")
+			}
+			if *format != tt.format {
+				t.Errorf("format = %q, want %q", *format, tt.format)
+			}
+			if got := fs.Args(); !reflect.DeepEqual(got, tt.positional) && !(len(got) == 0 && len(tt.positional) == 0) {
+				t.Errorf("args = %q, want %q", got, tt.positional)
+			}
+		})
+	}
+}
+
+func TestParseFlagsUnknown(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if code, ok := parseFlags(fs, []string{"today", "--nope"}); ok || code != exitUsage {
+		t.Errorf("parseFlags = %d, %v, want %d, false", code, ok, exitUsage)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC) // Thursday
+	tests := map[string]string{
+		"":           "2026-10-15",
+		"today":      "2026-10-15",
+		"Yesterday":  "2026-10-14",
+		"monday":     "2026-10-12",
+		"thursday":   "2026-10-08",
+		"2026-01-31": "2026-01-31",
+	}
+	for input, want := range tests {
+		got, err := parseDate(input, now)
+		if err != nil || got != want {
+			t.Errorf("parseDate(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+
+	if _, err := parseDate("31.01.2026", now); exitCodeFor(err) != exitUsage {
+		t.Errorf("parseDate(31.01.2026) error = %v, want a usage error", err)
+	}
+}
+
+func TestParseHours(t *testing.T) {
+	valid := map[string]float64{
+		"1.5":  1.5,
+		"2":    2,
+		"1:30": 1.5,
+		"0:45": 0.75,
+		"168":  168,
+	}
+	for input, want := range valid {
+		if got, err := parseHours(input); err != nil || got != want {
+			t.Errorf("parseHours(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+
+	invalid := []string{"", "0", "-1", "0:00", "1:60", "1:-5", "abc", "1:30:00",
+		"NaN", "nan", "Inf", "+Inf", "-Inf", "infinity", "1e309", "1e300", "168.5", "169", "NaN:00", "1:NaN", "Inf:00", "1e300:00"}
+	for _, input := range invalid {
+		if _, err := parseHours(input); err == nil {
+			t.Errorf("parseHours(%q) succeeded, want an error", input)
+		}
+	}
+}
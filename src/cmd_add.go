@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(command{
+		name:    "add",
+		summary: "book a time entry",
+		run:     runAdd,
+		flags:   func(string) *flag.FlagSet { fs, _, _ := newAddFlags(); return fs },
+	})
+}
+
+// optionalBool is a bool flag that remembers whether it was given
+type optionalBool struct {
+	value *bool
+}
+
+func (b *optionalBool) String() string {
+	if b.value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b.value)
+}
+
+func (b *optionalBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	b.value = &v
+	return nil
+}
+
+func (b *optionalBool) IsBoolFlag() bool { return true }
+
+// addFlags are the flags of 'moco add'
+type addFlags struct {
+	task      *string
+	hours     *string
+	date      *string
+	desc      *string
+	ticketRef *string
+	tag       *string
+	billable  *optionalBool
+	quiet     *bool
+}
+
+func newAddFlags() (*flag.FlagSet, *addFlags, *bool) {
+	fs, demo := newFlagSet("add", "--task <id|name> --hours <1.5|1:30> --desc <text> [flags], or [flags] -- <description>")
+	flags := &addFlags{
+		task:      fs.String("task", "", "task ID or (part of) \"Project / Task\""),
+		hours:     fs.String("hours", "", "hours as decimal (1.5) or time (1:30)"),
+		date:      fs.String("date", "today", "YYYY-MM-DD, today, yesterday or a weekday"),
+		desc:      fs.String("desc", "", "description"),
+		ticketRef: fs.String("ticket", "", "ticket reference, detected from the description if empty"),
+		tag:       fs.String("tag", "", "tag"),
+		billable:  &optionalBool{},
+		quiet:     fs.Bool("quiet", false, "print only the ID of the new entry"),
+	}
+	fs.Var(flags.billable, "billable", "mark the entry billable (default from project and task)")
+	return fs, flags, demo
+}
+
+func runAdd(args []string) int {
+	fs, flags, demo := newAddFlags()
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	// The description may also follow "--", for text that starts with a dash
+	if fs.NArg() > 0 && *flags.desc == "" {
+		*flags.desc = strings.Join(fs.Args(), " ")
+	} else if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "moco add: unexpected arguments: %v\n", fs.Args())
+		return exitUsage
+	}
+
+	entryDate, err := parseDate(*flags.date, time.Now())
+	if err != nil {
+		return fail(err)
+	}
+
+	session, err := openSession(*demo)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.Close()
+
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	projects, err := loadProjects(ctx, session.client)
+	if err != nil {
+		return fail(err)
+	}
+	t, err := findTask(projects, *flags.task)
+	if err != nil {
+		return fail(err)
+	}
+
+	if flags.billable.value == nil {
+		defaultBillable := t.Billable()
+		flags.billable.value = &defaultBillable
+	}
+
+	entry, err := newTimeEntry(entryInput{
+		ProjectID:   t.Project.ID,
+		TaskID:      t.Task.ID,
+		Date:        entryDate,
+		Hours:       *flags.hours,
+		Description: *flags.desc,
+		Ticket:      *flags.ticketRef,
+		Tag:         *flags.tag,
+		Billable:    flags.billable.value,
+	}, session.cfg.TicketRules)
+	if err != nil {
+		return fail(&usageError{err.Error()})
+	}
+
+	created, err := session.client.CreateActivity(ctx, entry)
+	if err != nil {
+		return fail(err)
+	}
+
+	if *flags.quiet {
+		fmt.Println(created.ID)
+	} else {
+		fmt.Printf("Booked %.2fh on %s for %s (#%d)\n", created.Hours, t.Label(), created.Date, created.ID)
+	}
+	return exitOK
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/denwerk/moco/src/api"
+)
+
+func init() {
+	registerCommand(command{
+		name:        "auth",
+		summary:     "keep the API key encrypted in ~/.moco (login|logout|status|unlock|lock)",
+		run:         runAuth,
+		subcommands: []string{"login", "logout", "status", "unlock", "lock"},
+		flags:       authFlags,
+	})
+}
+
+// minPassphraseLength keeps the passphrase from being trivially guessed
+const minPassphraseLength = 8
+
+func runAuth(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: moco auth login|logout|status|unlock|lock [flags]")
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "login":
+		return runAuthLogin(args[1:])
+	case "logout":
+		return runAuthLogout(args[1:])
+	case "status":
+		return runAuthStatus(args[1:])
+	case "unlock":
+		return runAuthUnlock(args[1:])
+	case "lock":
+		return runAuthLock(args[1:])
+	case "agent":
+		return runAgent(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "moco auth: unknown subcommand %q, use login, logout, status, unlock or lock\n", args[0])
+	return exitUsage
+}
+
+// authDir parses the flags of an auth subcommand and returns the state
+// directory of the selected profile
+func authDir(name, usage string, args []string, setup func(fs *flag.FlagSet)) (string, int, bool) {
+	fs, _ := newFlagSet("auth "+name, usage)
+	if setup != nil {
+		setup(fs)
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return "", code, false
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "moco auth %s: unexpected arguments: %v\n", name, fs.Args())
+		return "", exitUsage, false
+	}
+
+	stateProfile = selectedProfile()
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", fail(err), false
+	}
+	return dir, exitOK, true
+}
+
+// authFlags returns the flag set of an auth subcommand, for completion
+func authFlags(sub string) *flag.FlagSet {
+	fs, _ := newFlagSet("auth "+sub, "")
+	if sub == "login" {
+		authLoginFlags(fs)
+	}
+	return fs
+}
+
+// authLoginFlags adds the flags of 'auth login' and returns --no-verify
+func authLoginFlags(fs *flag.FlagSet) *bool {
+	return fs.Bool("no-verify", false, "store the key without checking it against Moco")
+}
+
+// runAuthLogin asks for the API key and a passphrase, checks the key
+// against Moco and stores it encrypted. The key is unlocked right away.
+func runAuthLogin(args []string) int {
+	var noVerify *bool
+	dir, code, ok := authDir("login", "[--no-verify]", args, func(fs *flag.FlagSet) {
+		noVerify = authLoginFlags(fs)
+	})
+	if !ok {
+		return code
+	}
+
+	apiKey, err := passphrasePrompt("Moco API key (from your Moco profile, Integrations): ")
+	if err != nil {
+		return fail(usageErrorf("moco auth login needs a terminal to ask for the key"))
+	}
+	if apiKey == "" {
+		return fail(usageErrorf("no API key given"))
+	}
+
+	flags := configFlags
+	flags.APIKey = apiKey
+	cfg, err := loadConfig(flags)
+	ttl := DefaultCredentialTTL
+	switch {
+	case err == nil:
+		ttl = cfg.CredentialTTL
+		if !*noVerify {
+			if err := verifyAPIKey(cfg); err != nil {
+				return fail(err)
+			}
+		}
+	case !*noVerify:
+		fmt.Fprintln(os.Stderr, "The key can't be checked without a Moco domain; configure one or use --no-verify.")
+		return fail(err)
+	}
+
+	passphrase, err := newPassphrase()
+	if err != nil {
+		return fail(err)
+	}
+	store, err := sealAPIKey(apiKey, passphrase)
+	if err != nil {
+		return fail(err)
+	}
+	if err := SaveCredentials(dir, store); err != nil {
+		return fail(err)
+	}
+	fmt.Printf("Stored the API key encrypted in %s\n", filepath.Join(dir, credentialsFile))
+
+	if err := startAgent(dir, apiKey, ttl); err != nil {
+		fmt.Fprintf(os.Stderr, "moco: the key stays locked: %v\n", err)
+	} else {
+		fmt.Printf("Unlocked for %s\n", ttl)
+	}
+
+	// A plaintext key elsewhere would still win over the stored one
+	passphrasePrompt = nil
+	if cfg, err := loadConfig(configFlags); err == nil && cfg.Sources["MOCO_API_KEY"] != "credential store" {
+		fmt.Printf("Note: MOCO_API_KEY from %s takes precedence over the stored key; remove it there.\n", cfg.Sources["MOCO_API_KEY"])
+	}
+	return exitOK
+}
+
+// verifyAPIKey asks Moco who the key belongs to
+func verifyAPIKey(cfg *Config) error {
+	client, err := newClient(cfg, api.WithLogger(nil), api.WithRetryPolicy(api.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := requestContext()
+	defer cancel()
+
+	session, err := client.Session(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("The key belongs to user #%d on %s\n", session.ID, client.BaseURL())
+	return nil
+}
+
+// newPassphrase asks for a new passphrase twice
+func newPassphrase() (string, error) {
+	for {
+		passphrase, err := passphrasePrompt("New passphrase to encrypt the key: ")
+		if err != nil {
+			return "", err
+		}
+		if len(passphrase) < minPassphraseLength {
+			fmt.Fprintf(os.Stderr, "Use at least %d characters.\n", minPassphraseLength)
+			continue
+		}
+		again, err := passphrasePrompt("Repeat the passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again == passphrase {
+			return passphrase, nil
+		}
+		fmt.Fprintln(os.Stderr, "The passphrases don't match, try again.")
+	}
+}
+
+// runAuthLogout locks the key and removes the credential store
+func runAuthLogout(args []string) int {
+	dir, code, ok := authDir("logout", "", args, nil)
+	if !ok {
+		return code
+	}
+
+	stopAgent(dir)
+	store, err := LoadCredentials(dir)
+	if err != nil {
+		return fail(err)
+	}
+	if store == nil {
+		fmt.Println("No API key stored")
+		return exitOK
+	}
+	if err := RemoveCredentials(dir); err != nil {
+		return fail(err)
+	}
+	fmt.Println("Removed the stored API key")
+	return exitOK
+}
+
+// runAuthStatus tells whether a key is stored and unlocked. It exits with
+// 1 when there is no stored key.
+func runAuthStatus(args []string) int {
+	dir, code, ok := authDir("status", "", args, nil)
+	if !ok {
+		return code
+	}
+
+	store, err := LoadCredentials(dir)
+	if err != nil {
+		return fail(err)
+	}
+	profile := "default profile"
+	if stateProfile != "" {
+		profile = "profile " + stateProfile
+	}
+	if store == nil {
+		fmt.Printf("No API key stored for the %s\n", profile)
+		return exitError
+	}
+
+	fmt.Printf("API key for the %s stored in %s since %s\n",
+		profile, filepath.Join(dir, credentialsFile), store.CreatedAt.Format("2006-01-02"))
+	if status, ok := agentGet(dir); ok {
+		fmt.Printf("Unlocked until %s\n", status.Expires.Format("2006-01-02 15:04"))
+	} else {
+		fmt.Println("Locked; the next command asks for the passphrase")
+	}
+	return exitOK
+}
+
+// runAuthUnlock asks for the passphrase now rather than at the next command,
+// e.g. before running commands that read stdin
+func runAuthUnlock(args []string) int {
+	dir, code, ok := authDir("unlock", "", args, nil)
+	if !ok {
+		return code
+	}
+
+	store, err := LoadCredentials(dir)
+	if err != nil {
+		return fail(err)
+	}
+	if store == nil {
+		return fail(&ConfigError{"no API key stored, run 'moco auth login' first"})
+	}
+	if _, err := unlockAPIKey(dir, authTTL()); err != nil {
+		return fail(err)
+	}
+	if status, ok := agentGet(dir); ok {
+		fmt.Printf("Unlocked until %s\n", status.Expires.Format("2006-01-02 15:04"))
+	}
+	return exitOK
+}
+
+// runAuthLock makes the agent forget the key before its TTL runs out
+func runAuthLock(args []string) int {
+	dir, code, ok := authDir("lock", "", args, nil)
+	if !ok {
+		return code
+	}
+
+	if stopAgent(dir) {
+		fmt.Println("Locked")
+	} else {
+		fmt.Println("Not unlocked")
+	}
+	return exitOK
+}
+
+// authTTL is the configured MOCO_CREDENTIAL_TTL, or the default if the
+// configuration can't be loaded
+func authTTL() time.Duration {
+	flags := configFlags
+	flags.APIKey = "-" // Keeps loadConfig from unlocking the store itself
+	cfg, err := loadConfig(flags)
+	if err != nil {
+		return DefaultCredentialTTL
+	}
+	return cfg.CredentialTTL
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+func init() {
+	registerCommand(command{
+		name:    "copy",
+		summary: "copy a day's bookings to another day or range",
+		run:     runCopy,
+		flags:   func(string) *flag.FlagSet { fs, _, _ := newCopyFlags(); return fs },
+	})
+}
+
+// copyFlags are the flags of 'moco copy'
+type copyFlags struct {
+	source   *string
+	to       *string
+	until    *string
+	ids      *string
+	weekends *bool
+	yes      *bool
+}
+
+func newCopyFlags() (*flag.FlagSet, *copyFlags, *bool) {
+	fs, demo := newFlagSet("copy", "[--source <date>] [--to <date>] [--until <date>] [--ids 1,2] [--yes]")
+	flags := &copyFlags{
+		source:   fs.String("source", "yesterday", "day to copy from"),
+		to:       fs.String("to", "today", "day to copy to, or first day of the range"),
+		until:    fs.String("until", "", "last day of the target range"),
+		ids:      fs.String("ids", "", "comma-separated IDs of the entries to copy (default all of the day)"),
+		weekends: fs.Bool("weekends", false, "include Saturdays and Sundays in a target range"),
+		yes:      fs.Bool("yes", false, "copy without reviewing hours and descriptions"),
+	}
+	return fs, flags, demo
+}
+
+func runCopy(args []string) int {
+	fs, flags, demo := newCopyFlags()
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "moco copy: unexpected arguments: %v\n", fs.Args())
+		return exitUsage
+	}
+
+	now := time.Now()
+	sourceDate, err := parseDate(*flags.source, now)
+	if err != nil {
+		return fail(err)
+	}
+	targetFrom, err := parseDate(*flags.to, now)
+	if err != nil {
+		return fail(err)
+	}
+	targetUntil := ""
+	if *flags.until != "" {
+		if targetUntil, err = parseDate(*flags.until, now); err != nil {
+			return fail(err)
+		}
+	}
+	dates, err := copyDates(targetFrom, targetUntil, *flags.weekends)
+	if err != nil {
+		return fail(err)
+	}
+
+	session, err := openSession(*demo)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.Close()
+
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	entries, _, err := session.client.Activities(ctx, sourceDate, sourceDate)
+	if err != nil {
+		return fail(err)
+	}
+	sortEntries(entries)
+	if *flags.ids != "" {
+		if entries, err = selectEntries(entries, *flags.ids); err != nil {
+			return fail(err)
+		}
+	}
+	if len(entries) == 0 {
+		fmt.Printf("Nothing booked on %s\n", sourceDate)
+		return exitOK
+	}
+
+	items := newCopyItems(entries)
+	if !*flags.yes {
+		fmt.Printf("Copying %d entries from %s to %s. Press enter to keep a value, enter - as hours to leave an entry out.\n",
+			len(items), sourceDate, describeDates(dates))
+		reviewCopyItems(items)
+	}
+
+	copies, err := buildCopies(items, dates, session.cfg.TicketRules)
+	if err != nil {
+		return fail(err)
+	}
+	copies, skipped, err := skipBooked(ctx, session.client, copies)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Println()
+	for _, c := range copies {
+		fmt.Printf("+ %s  %5.2fh  %s / %s  %s\n", c.Date, c.Hours, c.Project.Name, c.Task.Name, c.Description)
+	}
+	if skipped > 0 {
+		fmt.Printf("%d entries are already booked and are left out\n", skipped)
+	}
+	if len(copies) == 0 {
+		fmt.Println("Nothing to book")
+		return exitOK
+	}
+	if !*flags.yes && !confirm(fmt.Sprintf("Book %d entries?", len(copies))) {
+		fmt.Println("Nothing booked")
+		return exitOK
+	}
+
+	failed := 0
+	var lastErr error
+	for _, c := range copies {
+		created, err := session.client.CreateActivity(ctx, c)
+		if err != nil {
+			failed++
+			lastErr = err
+			fmt.Printf("failed %s %s / %s: %s\n", c.Date, c.Project.Name, c.Task.Name, describeError(err))
+			continue
+		}
+		fmt.Printf("booked #%d\n", created.ID)
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d of %d entries failed. Run the same copy again to retry them; booked ones are left out.\n", failed, len(copies))
+		return exitCodeFor(lastErr)
+	}
+	fmt.Printf("\nBooked %d entries\n", len(copies))
+	return exitOK
+}
+
+// selectEntries picks the entries with the given comma-separated IDs
+func selectEntries(entries []types.TimeEntry, ids string) ([]types.TimeEntry, error) {
+	byID := map[int]types.TimeEntry{}
+	for _, e := range entries {
+		byID[e.ID] = e
+	}
+
+	var selected []types.TimeEntry
+	for _, field := range strings.Split(ids, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, usageErrorf("invalid entry ID %q", field)
+		}
+		e, ok := byID[id]
+		if !ok {
+			return nil, usageErrorf("entry #%d is not booked on the source day", id)
+		}
+		selected = append(selected, e)
+	}
+	return selected, nil
+}
+
+// reviewCopyItems lets the user adjust hours and description of each item
+func reviewCopyItems(items []copyItem) {
+	for i := range items {
+		item := &items[i]
+		fmt.Printf("\n%s / %s\n", item.Source.Project.Name, item.Source.Task.Name)
+		if hours := prompt(fmt.Sprintf("  Hours [%s]: ", item.Hours)); hours != "" {
+			item.Hours = hours
+		}
+		if item.leftOut() {
+			continue
+		}
+		if desc := prompt(fmt.Sprintf("  Description [%s]: ", item.Description)); desc != "" {
+			item.Description = desc
+		}
+	}
+}
+
+func describeDates(dates []string) string {
+	if len(dates) == 1 {
+		return dates[0]
+	}
+	return fmt.Sprintf("%s to %s (%d days)", dates[0], dates[len(dates)-1], len(dates))
+}
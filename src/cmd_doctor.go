@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/denwerk/moco/src/api"
+)
+
+func init() {
+	registerCommand(command{
+		name:    "doctor",
+		summary: "check configuration, connectivity and local state",
+		run:     runDoctor,
+		flags:   func(string) *flag.FlagSet { fs, _ := newFlagSet("doctor", ""); return fs },
+	})
+}
+
+// checkStatus is the outcome of a doctor check
+type checkStatus string
+
+const (
+	checkPass checkStatus = "ok"
+	checkWarn checkStatus = "warn"
+	checkFail checkStatus = "FAIL"
+	checkSkip checkStatus = "skip"
+)
+
+// checkResult is what a check found and, unless it passed, how to fix it
+type checkResult struct {
+	Status checkStatus
+	Detail string
+	Fix    string
+}
+
+// maxClockSkew is how far the local clock may be off before doctor fails
+const maxClockSkew = 2 * time.Minute
+
+// doctor carries what earlier checks found to later ones
+type doctor struct {
+	demo     bool
+	cfg      *Config
+	baseURL  *url.URL
+	resolved bool // The API host has an address
+}
+
+func runDoctor(args []string) int {
+	fs, demo := newFlagSet("doctor", "")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	d := &doctor{demo: *demo}
+	if *demo {
+		cfg, stop, err := startDemo()
+		if err != nil {
+			return fail(err)
+		}
+		defer stop()
+		d.cfg = cfg
+	}
+
+	checks := []struct {
+		name string
+		run  func() checkResult
+	}{
+		{"Config sources", d.checkConfig},
+		{"Domain", d.checkDomain},
+		{"DNS", d.checkDNS},
+		{"TLS", d.checkTLS},
+		{"API key", d.checkAPIKey},
+		{"Clock", d.checkClock},
+		{"State directory", d.checkStateDir},
+		{"Log directory", d.checkLogDir},
+		{"Key bindings", d.checkKeys},
+		{"Theme", d.checkTheme},
+	}
+
+	failed := 0
+	for _, check := range checks {
+		result := check.run()
+		fmt.Printf("[%-4s] %-16s %s\n", result.Status, check.name, result.Detail)
+		if result.Fix != "" && result.Status != checkPass {
+			fmt.Printf("       %-16s fix: %s\n", "", result.Fix)
+		}
+		if result.Status == checkFail {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d of %d checks failed\n", failed, len(checks))
+		return exitError
+	}
+	fmt.Println("\nAll checks passed")
+	return exitOK
+}
+
+// checkConfig loads the settings and reports where they come from
+func (d *doctor) checkConfig() checkResult {
+	if d.demo {
+		return checkResult{Status: checkSkip, Detail: "demo mode uses a built-in account"}
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		fix := "correct the setting named above"
+		if err == ErrMissingEnvVars {
+			fix = "put domain and api_key in ~/.config/moco/config.toml (see config.example.toml), or set MOCO_DOMAIN and MOCO_API_KEY"
+		}
+		return checkResult{Status: checkFail, Detail: err.Error(), Fix: fix}
+	}
+	d.cfg = cfg
+
+	var sources []string
+	switch {
+	case cfg.File == "":
+		sources = append(sources, "no config file")
+	case cfg.Profile != "":
+		sources = append(sources, fmt.Sprintf("profile %s of %s", cfg.Profile, cfg.File))
+	default:
+		sources = append(sources, cfg.File)
+	}
+	for _, name := range []string{"MOCO_DOMAIN", "MOCO_API_KEY", "MOCO_BASE_URL"} {
+		if source := cfg.Sources[name]; source != "" {
+			sources = append(sources, name+" from "+source)
+		}
+	}
+	return checkResult{Status: checkPass, Detail: strings.Join(sources, ", ")}
+}
+
+// checkDomain shows the API root that MOCO_DOMAIN or MOCO_BASE_URL resolve
+// to; LoadConfig has already rejected invalid ones
+func (d *doctor) checkDomain() checkResult {
+	if d.cfg == nil {
+		return checkResult{Status: checkSkip, Detail: "no configuration"}
+	}
+
+	u, err := url.Parse(d.cfg.MocoBaseURL)
+	if err != nil {
+		return checkResult{Status: checkFail, Detail: err.Error(), Fix: "check MOCO_DOMAIN and MOCO_BASE_URL"}
+	}
+	d.baseURL = u
+	detail := "API root " + d.cfg.MocoBaseURL
+	if u.Scheme == "http" && !isLoopback(u.Hostname()) {
+		return checkResult{
+			Status: checkWarn,
+			Detail: detail + ", the API key is sent unencrypted",
+			Fix:    "use an https:// URL in MOCO_BASE_URL",
+		}
+	}
+	return checkResult{Status: checkPass, Detail: detail}
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (d *doctor) checkDNS() checkResult {
+	if d.baseURL == nil {
+		return checkResult{Status: checkSkip, Detail: "no valid API root"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	host := d.baseURL.Hostname()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return checkResult{
+			Status: checkFail,
+			Detail: fmt.Sprintf("can't resolve %s: %v", host, err),
+			Fix:    "check the spelling of MOCO_DOMAIN and your network, VPN or DNS settings",
+		}
+	}
+	d.resolved = true
+	return checkResult{Status: checkPass, Detail: fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", "))}
+}
+
+func (d *doctor) checkTLS() checkResult {
+	if !d.resolved {
+		return checkResult{Status: checkSkip, Detail: "the API host doesn't resolve"}
+	}
+	if d.baseURL.Scheme != "https" {
+		return checkResult{Status: checkSkip, Detail: "the API root uses plain HTTP"}
+	}
+
+	port := d.baseURL.Port()
+	if port == "" {
+		port = "443"
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(d.baseURL.Hostname(), port), &tls.Config{ServerName: d.baseURL.Hostname()})
+	if err != nil {
+		return checkResult{
+			Status: checkFail,
+			Detail: fmt.Sprintf("TLS connection failed: %v", err),
+			Fix:    "check firewalls and proxies; certificate errors can also come from a wrong system clock or TLS inspection",
+		}
+	}
+	defer conn.Close()
+
+	cert := conn.ConnectionState().PeerCertificates[0]
+	return checkResult{
+		Status: checkPass,
+		Detail: fmt.Sprintf("certificate for %s valid until %s", cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02")),
+	}
+}
+
+func (d *doctor) checkAPIKey() checkResult {
+	if !d.resolved {
+		return checkResult{Status: checkSkip, Detail: "the API host doesn't resolve"}
+	}
+
+	client, err := newClient(d.cfg, api.WithLogger(nil), api.WithRetryPolicy(api.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		return checkResult{Status: checkFail, Detail: err.Error()}
+	}
+
+	ctx, cancel := requestContext()
+	defer cancel()
+
+	session, err := client.Session(ctx)
+	var apiErr *api.Error
+	switch {
+	case errors.As(err, &apiErr) && (apiErr.StatusCode == 401 || apiErr.StatusCode == 403):
+		return checkResult{
+			Status: checkFail,
+			Detail: "Moco refused the API key",
+			Fix:    "copy your personal API key from Moco (Profile > Integrations) into MOCO_API_KEY",
+		}
+	case err != nil:
+		return checkResult{
+			Status: checkFail,
+			Detail: describeError(err),
+			Fix:    "make sure the API root is right and Moco is reachable, then try again",
+		}
+	}
+	return checkResult{Status: checkPass, Detail: fmt.Sprintf("the key belongs to user #%d", session.ID)}
+}
+
+// checkClock compares the local clock with the Date header of Moco
+func (d *doctor) checkClock() checkResult {
+	if !d.resolved {
+		return checkResult{Status: checkSkip, Detail: "the API host doesn't resolve"}
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	before := time.Now()
+	resp, err := httpClient.Head(d.baseURL.String())
+	if err != nil {
+		return checkResult{Status: checkSkip, Detail: fmt.Sprintf("no response from Moco: %v", err)}
+	}
+	resp.Body.Close()
+	local := before.Add(time.Since(before) / 2)
+
+	server, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return checkResult{Status: checkSkip, Detail: "Moco sent no Date header"}
+	}
+
+	// The Date header has a resolution of one second
+	skew := local.Sub(server).Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxClockSkew {
+		return checkResult{
+			Status: checkFail,
+			Detail: fmt.Sprintf("the local clock is off by %s", skew),
+			Fix:    "turn on automatic time synchronisation (e.g. timedatectl set-ntp true)",
+		}
+	}
+	return checkResult{Status: checkPass, Detail: fmt.Sprintf("within %s of Moco", skew+time.Second)}
+}
+
+func (d *doctor) checkStateDir() checkResult {
+	dir, err := getConfigDir()
+	if err != nil {
+		return checkResult{
+			Status: checkFail,
+			Detail: err.Error(),
+			Fix:    "make sure ~/.moco (or MOCO_CONFIG_DIR) is a directory owned by you",
+		}
+	}
+	if err := probeWrite(dir); err != nil {
+		return checkResult{
+			Status: checkFail,
+			Detail: fmt.Sprintf("can't write to %s: %v", dir, err),
+			Fix:    fmt.Sprintf("chown $USER %s && chmod 700 %s", dir, dir),
+		}
+	}
+	return checkResult{Status: checkPass, Detail: dir + " is writable"}
+}
+
+// checkLogDir checks that the TUI can write to the logs directory it creates
+// in the working directory. The directory itself isn't created here; if it
+// doesn't exist yet, its parent has to be writable.
+func (d *doctor) checkLogDir() checkResult {
+	dir := filepath.Join(workingDir(), "logs")
+	info, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		parent := filepath.Dir(dir)
+		if err := probeWrite(parent); err != nil {
+			return checkResult{
+				Status: checkFail,
+				Detail: fmt.Sprintf("can't create %s: %v", dir, err),
+				Fix:    "start moco from a directory you can write to",
+			}
+		}
+		return checkResult{Status: checkPass, Detail: dir + " will be created"}
+	case err != nil:
+		return checkResult{Status: checkFail, Detail: err.Error(), Fix: "start moco from another directory"}
+	case !info.IsDir():
+		return checkResult{
+			Status: checkFail,
+			Detail: dir + " is not a directory",
+			Fix:    fmt.Sprintf("remove %s, or start moco from another directory", dir),
+		}
+	}
+	if err := probeWrite(dir); err != nil {
+		return checkResult{
+			Status: checkFail,
+			Detail: fmt.Sprintf("can't write to %s: %v", dir, err),
+			Fix:    fmt.Sprintf("chown $USER %s, or start moco from another directory", dir),
+		}
+	}
+	return checkResult{Status: checkPass, Detail: dir + " is writable"}
+}
+
+// checkKeys checks the [keys] table of the config file for unknown actions,
+// invalid keys and conflicts, which would keep the TUI from starting
+func (d *doctor) checkKeys() checkResult {
+	if d.cfg == nil {
+		return checkResult{Status: checkSkip, Detail: "no configuration"}
+	}
+	if _, err := newKeyMap(d.cfg.Keys); err != nil {
+		return checkResult{Status: checkFail, Detail: err.Error(), Fix: "correct the [keys] table in " + d.cfg.File}
+	}
+	if len(d.cfg.Keys) == 0 {
+		return checkResult{Status: checkPass, Detail: "defaults"}
+	}
+	return checkResult{Status: checkPass, Detail: fmt.Sprintf("%d actions rebound in %s", len(d.cfg.Keys), d.cfg.File)}
+}
+
+// checkTheme checks the theme setting and the [colors] table and shows
+// which theme the TUI would use
+func (d *doctor) checkTheme() checkResult {
+	if d.cfg == nil {
+		return checkResult{Status: checkSkip, Detail: "no configuration"}
+	}
+	theme, reason, err := loadTheme(d.cfg)
+	if err != nil {
+		return checkResult{Status: checkFail, Detail: err.Error(), Fix: "correct the theme setting or the [colors] table"}
+	}
+	detail := fmt.Sprintf("%s, %s", theme.Name, reason)
+	if len(d.cfg.Colors) > 0 && os.Getenv("NO_COLOR") == "" {
+		detail += fmt.Sprintf(", %d colours from %s", len(d.cfg.Colors), d.cfg.File)
+	}
+	return checkResult{Status: checkPass, Detail: detail}
+}
+
+// probeWrite creates and removes a file in dir
+func probeWrite(dir string) error {
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func workingDir() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return dir
+}
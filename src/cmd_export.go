@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/denwerk/moco/src/export"
+	"github.com/denwerk/moco/src/types"
+)
+
+func init() {
+	registerCommand(command{
+		name:    "export",
+		summary: "export time entries as CSV, JSON or iCalendar",
+		run:     runExport,
+		flags:   func(string) *flag.FlagSet { fs, _, _ := newExportFlags(); return fs },
+	})
+}
+
+// exportFlags are the flags of 'moco export'
+type exportFlags struct {
+	from   *string
+	to     *string
+	filter export.Filter
+	format *string
+	output *string
+	locale *string
+}
+
+func newExportFlags() (*flag.FlagSet, *exportFlags, *bool) {
+	fs, demo := newFlagSet("export", "[preset] [--from <date>] [--to <date>] [--project p] [--customer c] [--task t] [--format csv|json|ics] [-o file]")
+	flags := &exportFlags{
+		from: fs.String("from", "", "first day, YYYY-MM-DD, today, yesterday or a weekday"),
+		to:   fs.String("to", "", "last day (default today when --from is given)"),
+	}
+	fs.StringVar(&flags.filter.Project, "project", "", "only entries of projects with this ID or name part")
+	fs.StringVar(&flags.filter.Customer, "customer", "", "only entries of customers with this ID or name part")
+	fs.StringVar(&flags.filter.Task, "task", "", "only entries of tasks with this ID or name part")
+	flags.format = fs.String("format", "", "csv, json, ndjson or ics (default from the file extension, else csv)")
+	flags.output = fs.String("o", "", "write to this file instead of stdout")
+	flags.locale = fs.String("locale", export.LocaleFromEnv(), "locale for CSV numbers, e.g. de for German Excel")
+	return fs, flags, demo
+}
+
+func runExport(args []string) int {
+	fs, flags, demo := newExportFlags()
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "moco export: expected at most one preset, got %v\n", fs.Args())
+		return exitUsage
+	}
+
+	if *flags.format == "" {
+		*flags.format = "csv"
+		if guessed, ok := export.FormatFor(*flags.output); ok {
+			*flags.format = guessed
+		}
+	}
+	if _, ok := export.FormatFor("." + *flags.format); !ok {
+		return fail(usageErrorf("unknown format %q, use one of %s", *flags.format, strings.Join(export.Formats, ", ")))
+	}
+
+	r, err := resolveRange(fs.Arg(0), *flags.from, *flags.to, time.Now())
+	if err != nil {
+		return fail(err)
+	}
+
+	session, err := openSession(*demo)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.Close()
+
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	entries, info, err := session.client.Activities(ctx, r.From, r.To)
+	if err != nil {
+		return fail(err)
+	}
+	if info.Truncated {
+		fmt.Fprintf(os.Stderr, "moco: list truncated, exporting %d of %d entries\n", info.Fetched, info.Total)
+	}
+	entries = flags.filter.Apply(entries)
+	sortEntries(entries)
+
+	opts := exportOptions(session.cfg, *flags.locale)
+	if *flags.output == "" {
+		err = export.Write(os.Stdout, *flags.format, entries, opts)
+	} else {
+		err = exportFile(*flags.output, *flags.format, entries, opts, true)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if *flags.output != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d entries to %s\n", len(entries), *flags.output)
+	}
+	return exitOK
+}
+
+func exportOptions(cfg *Config, locale string) export.Options {
+	return export.Options{
+		Dialect: export.DialectFor(locale),
+		Now:     time.Now(),
+		Domain:  cfg.MocoDomain,
+	}
+}
+
+// exportFile writes entries to the named file, which is replaced only once
+// the export is complete. Unless overwrite is set, an existing file is left
+// alone and os.ErrExist returned.
+func exportFile(name, format string, entries []types.TimeEntry, opts export.Options, overwrite bool) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".moco-export-*")
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := export.Write(tmp, format, entries, opts); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if overwrite {
+		return os.Rename(tmp.Name(), name)
+	}
+
+	// A hard link fails rather than replace the file, even if it appeared
+	// meanwhile; file systems without links get the check and the rename
+	err = os.Link(tmp.Name(), name)
+	if err == nil || errors.Is(err, os.ErrExist) {
+		return err
+	}
+	if _, statErr := os.Lstat(name); statErr == nil {
+		return &os.PathError{Op: "export", Path: name, Err: os.ErrExist}
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denwerk/moco/src/api"
+	"github.com/denwerk/moco/src/types"
+)
+
+func init() {
+	registerCommand(command{
+		name:    "import",
+		summary: "book time entries from a CSV file",
+		run:     runImport,
+		flags:   func(string) *flag.FlagSet { fs, _, _ := newImportFlags(); return fs },
+	})
+}
+
+// importColumns maps accepted header names to the field they fill. Files
+// written by 'moco export' can be imported as they are.
+var importColumns = map[string]string{
+	"date":        "date",
+	"hours":       "hours",
+	"project":     "project",
+	"task":        "task",
+	"description": "description",
+	"desc":        "description",
+	"billable":    "billable",
+	"tag":         "tag",
+	"ticket":      "ticket",
+	"remote_url":  "ticket",
+}
+
+// importRow is one line of an import file
+type importRow struct {
+	Line   int
+	Fields map[string]string
+	Task   taskRef
+	Entry  types.TimeEntry
+	Booked int   // ID of the existing entry the row matches, if any
+	Err    error // Why the row can't be booked
+}
+
+// String shows the row as it would be booked, or as read if it is invalid
+func (r *importRow) String() string {
+	if r.Err != nil {
+		f := r.Fields
+		return fmt.Sprintf("%s  %s  %s / %s  %s", f["date"], f["hours"], f["project"], f["task"], f["description"])
+	}
+	return fmt.Sprintf("%s  %5.2fh  %s  %s", r.Entry.Date, r.Entry.Hours, r.Task.Label(), r.Entry.Description)
+}
+
+// importFlags are the flags of 'moco import'
+type importFlags struct {
+	dryRun      *bool
+	yes         *bool
+	skipInvalid *bool
+}
+
+func newImportFlags() (*flag.FlagSet, *importFlags, *bool) {
+	fs, demo := newFlagSet("import", "<file.csv|-> [--dry-run] [--yes] [--skip-invalid]")
+	flags := &importFlags{
+		dryRun:      fs.Bool("dry-run", false, "only show what would be booked"),
+		yes:         fs.Bool("yes", false, "book without asking for confirmation"),
+		skipInvalid: fs.Bool("skip-invalid", false, "book the valid rows even if some are invalid"),
+	}
+	return fs, flags, demo
+}
+
+func runImport(args []string) int {
+	fs, flags, demo := newImportFlags()
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	name := fs.Arg(0)
+	var in io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fail(usageErrorf("%v", err))
+		}
+		defer f.Close()
+		in = f
+	} else if !*flags.dryRun && !*flags.yes {
+		return fail(usageErrorf("reading from stdin needs --yes or --dry-run, as there is no way to confirm"))
+	}
+
+	rows, err := readImportRows(in)
+	if err != nil {
+		return fail(err)
+	}
+	if len(rows) == 0 {
+		fmt.Println("Nothing to import")
+		return exitOK
+	}
+
+	session, err := openSession(*demo)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.Close()
+
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	projects, err := loadProjects(ctx, session.client)
+	if err != nil {
+		return fail(err)
+	}
+	for _, row := range rows {
+		if row.Err == nil {
+			row.Entry, row.Task, row.Err = resolveImportRow(row, projects, session.cfg)
+		}
+	}
+
+	if err := markBooked(ctx, session.client, rows); err != nil {
+		return fail(err)
+	}
+
+	pending, invalid := printImportPlan(os.Stdout, rows)
+	if invalid > 0 && !*flags.skipInvalid {
+		fmt.Fprintf(os.Stderr, "moco: %d invalid rows, fix them or pass --skip-invalid\n", invalid)
+		return exitUsage
+	}
+	if *flags.dryRun || len(pending) == 0 {
+		return exitOK
+	}
+	if !*flags.yes && !confirm(fmt.Sprintf("Book %d entries?", len(pending))) {
+		fmt.Println("Nothing booked")
+		return exitOK
+	}
+
+	return submitImport(ctx, session.client, pending)
+}
+
+// readImportRows parses a CSV file with a header row. Semicolon separated
+// files, as written by spreadsheets in many European locales, are detected.
+func readImportRows(in io.Reader) ([]*importRow, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, usageErrorf("invalid CSV: %v", err)
+	}
+	columns := make([]string, len(header))
+	found := map[string]bool{}
+	for i, name := range header {
+		columns[i] = importColumns[strings.ToLower(strings.TrimSpace(name))]
+		found[columns[i]] = true
+	}
+	for _, required := range []string{"date", "hours", "task", "description"} {
+		if !found[required] {
+			return nil, usageErrorf("CSV header lacks a %q column", required)
+		}
+	}
+
+	var rows []*importRow
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// FieldPos panics after a failed read, the parse error knows the line
+			row := &importRow{Fields: map[string]string{}, Err: fmt.Errorf("invalid CSV: %v", err)}
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				row.Line = parseErr.StartLine
+			}
+			rows = append(rows, row)
+			continue
+		}
+		line, _ := r.FieldPos(0)
+		row := &importRow{Line: line, Fields: map[string]string{}}
+		empty := true
+		for i, value := range record {
+			if i < len(columns) && columns[i] != "" {
+				row.Fields[columns[i]] = strings.TrimSpace(value)
+				empty = empty && strings.TrimSpace(value) == ""
+			}
+		}
+		if !empty {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// resolveImportRow finds the task of a row and validates it like the form does
+func resolveImportRow(row *importRow, projects []types.Project, cfg *Config) (types.TimeEntry, taskRef, error) {
+	f := row.Fields
+	t, err := findProjectTask(projects, f["project"], f["task"])
+	if err != nil {
+		return types.TimeEntry{}, taskRef{}, err
+	}
+
+	date, err := parseImportDate(f["date"])
+	if err != nil {
+		return types.TimeEntry{}, taskRef{}, err
+	}
+
+	billable := t.Billable()
+	if value := f["billable"]; value != "" {
+		if billable, err = parseYesNo(value); err != nil {
+			return types.TimeEntry{}, taskRef{}, err
+		}
+	}
+
+	entry, err := newTimeEntry(entryInput{
+		ProjectID:   t.Project.ID,
+		TaskID:      t.Task.ID,
+		Date:        date,
+		Hours:       strings.Replace(f["hours"], ",", ".", 1),
+		Description: f["description"],
+		Ticket:      f["ticket"],
+		Tag:         f["tag"],
+		Billable:    &billable,
+	}, cfg.TicketRules)
+	return entry, t, err
+}
+
+// parseImportDate accepts YYYY-MM-DD and the DD.MM.YYYY of German spreadsheets
+func parseImportDate(value string) (string, error) {
+	if day, err := time.Parse("02.01.2006", value); err == nil {
+		return day.Format("2006-01-02"), nil
+	}
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		return "", fmt.Errorf("invalid date %q: use YYYY-MM-DD or DD.MM.YYYY", value)
+	}
+	return value, nil
+}
+
+func parseYesNo(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y", "ja", "x":
+		return true, nil
+	case "no", "n", "nein":
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid billable value %q: use yes or no", value)
+	}
+	return b, nil
+}
+
+// markBooked looks up existing entries in the range of the file and marks
+// the rows that are already booked, so an import that failed halfway can
+// simply be run again. Each existing entry accounts for one row only.
+func markBooked(ctx context.Context, client *api.Client, rows []*importRow) error {
+	from, to := "", ""
+	for _, row := range rows {
+		if row.Err != nil {
+			continue
+		}
+		if from == "" || row.Entry.Date < from {
+			from = row.Entry.Date
+		}
+		if to == "" || row.Entry.Date > to {
+			to = row.Entry.Date
+		}
+	}
+	if from == "" {
+		return nil
+	}
+
+	existing, _, err := client.Activities(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	used := map[int]bool{}
+	for _, row := range rows {
+		if row.Err != nil {
+			continue
+		}
+		for _, e := range existing {
+			if !used[e.ID] && api.SameActivity(e, row.Entry) {
+				used[e.ID] = true
+				row.Booked = e.ID
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// printImportPlan lists what an import would do: "+" books a row, "="
+// skips one that is already booked and "!" marks an invalid one
+func printImportPlan(w io.Writer, rows []*importRow) (pending []*importRow, invalid int) {
+	booked := 0
+	for _, row := range rows {
+		switch {
+		case row.Err != nil:
+			invalid++
+			fmt.Fprintf(w, "! line %d: %v\n", row.Line, row.Err)
+		case row.Booked != 0:
+			booked++
+			fmt.Fprintf(w, "= line %d: %s (already booked as #%d)\n", row.Line, row, row.Booked)
+		default:
+			pending = append(pending, row)
+			fmt.Fprintf(w, "+ line %d: %s\n", row.Line, row)
+		}
+	}
+	fmt.Fprintf(w, "\n%d to book, %d already booked, %d invalid\n", len(pending), booked, invalid)
+	return pending, invalid
+}
+
+// submitImport books the rows one by one. It stops early when Moco is
+// unreachable, as the remaining rows would fail the same way.
+func submitImport(ctx context.Context, client *api.Client, rows []*importRow) int {
+	failed := 0
+	var lastErr error
+	for i, row := range rows {
+		created, err := client.CreateActivity(ctx, row.Entry)
+		if err != nil {
+			failed++
+			lastErr = err
+			fmt.Printf("failed line %d: %s\n", row.Line, describeError(err))
+			if shouldQueue(err) {
+				failed += len(rows) - i - 1
+				fmt.Printf("Moco is unreachable, stopping with %d rows left\n", len(rows)-i-1)
+				break
+			}
+			continue
+		}
+		fmt.Printf("booked line %d as #%d\n", row.Line, created.ID)
+	}
+
+	if failed == 0 {
+		fmt.Printf("\nBooked %d entries\n", len(rows))
+		return exitOK
+	}
+	fmt.Printf("\nBooked %d entries, %d failed. Run the same import again to retry them; booked rows are skipped.\n",
+		len(rows)-failed, failed)
+	return exitCodeFor(lastErr)
+}
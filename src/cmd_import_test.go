@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadImportRows(t *testing.T) {
+	in := "\ufeffDate;Hours;Project;Task;Description\n" +
+		"2026-10-15;1,5;ACME;Development;Review\n" +
+		";;;;\n" +
+		"2026-10-16;2;ACME;Development;Deploy\n"
+
+	rows, err := readImportRows(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2 (empty rows are skipped)", len(rows))
+	}
+	if rows[0].Line != 2 || rows[1].Line != 4 {
+		t.Errorf("lines = %d, %d, want 2, 4", rows[0].Line, rows[1].Line)
+	}
+	if got := rows[0].Fields["hours"]; got != "1,5" {
+		t.Errorf("hours = %q, want 1,5", got)
+	}
+}
+
+func TestReadImportRowsMissingColumn(t *testing.T) {
+	_, err := readImportRows(strings.NewReader("date,hours,project\n2026-10-15,1,ACME\n"))
+	if err == nil || !strings.Contains(err.Error(), `"task"`) {
+		t.Fatalf("err = %v, want missing task column", err)
+	}
+}
+
+func TestReadImportRowsBadQuote(t *testing.T) {
+	in := "date,hours,project,task,description\n" +
+		"\"2026-10-15\"x,1,ACME,Development,foo\n" +
+		"2026-10-16,1,ACME,Development,bar\n"
+
+	rows, err := readImportRows(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0].Err == nil || rows[0].Line != 2 {
+		t.Errorf("row 0 = line %d, err %v, want line 2 with a CSV error", rows[0].Line, rows[0].Err)
+	}
+	if rows[1].Err != nil || rows[1].Line != 3 {
+		t.Errorf("row 1 = line %d, err %v, want line 3 without error", rows[1].Line, rows[1].Err)
+	}
+}
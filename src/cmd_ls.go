@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(command{
+		name:    "ls",
+		summary: "list time entries for a date range",
+		run:     runLs,
+		flags:   func(string) *flag.FlagSet { fs, _, _ := newLsFlags(); return fs },
+	})
+}
+
+// lsFlags are the flags of 'moco ls'
+type lsFlags struct {
+	from   *string
+	to     *string
+	format *string
+}
+
+func newLsFlags() (*flag.FlagSet, *lsFlags, *bool) {
+	fs, demo := newFlagSet("ls", "[preset] [--from <date>] [--to <date>] [--format table|json|ndjson|csv]")
+	flags := &lsFlags{
+		from:   fs.String("from", "", "first day, YYYY-MM-DD, today, yesterday or a weekday"),
+		to:     fs.String("to", "", "last day (default today when --from is given)"),
+		format: fs.String("format", "table", "output format: "+strings.Join(outputFormats, ", ")),
+	}
+	return fs, flags, demo
+}
+
+func runLs(args []string) int {
+	fs, flags, demo := newLsFlags()
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "moco ls: expected at most one preset, got %v\n", fs.Args())
+		return exitUsage
+	}
+
+	r, err := resolveRange(fs.Arg(0), *flags.from, *flags.to, time.Now())
+	if err != nil {
+		return fail(err)
+	}
+
+	session, err := openSession(*demo)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.Close()
+
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	entries, info, err := session.client.Activities(ctx, r.From, r.To)
+	if err != nil {
+		return fail(err)
+	}
+	sortEntries(entries)
+
+	if err := writeEntries(os.Stdout, *flags.format, entries); err != nil {
+		return fail(err)
+	}
+	if info.Truncated {
+		fmt.Fprintf(os.Stderr, "moco: list truncated, showing %d of %d entries\n", info.Fetched, info.Total)
+	}
+	return exitOK
+}
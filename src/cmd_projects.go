@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/denwerk/moco/src/types"
+	"github.com/sahilm/fuzzy"
+)
+
+func init() {
+	registerCommand(command{
+		name:    "projects",
+		summary: "list assigned projects and tasks with their IDs",
+		run:     runProjects,
+		flags:   func(string) *flag.FlagSet { fs, _, _ := newProjectsFlags(); return fs },
+	})
+}
+
+// newProjectsFlags returns the flags of 'moco projects' and its --format
+func newProjectsFlags() (*flag.FlagSet, *string, *bool) {
+	fs, demo := newFlagSet("projects", "[query] [--format table|json|ids]")
+	format := fs.String("format", "table", "table, json (the projects as the API returns them) or ids (one task ID per line)")
+	return fs, format, demo
+}
+
+func runProjects(args []string) int {
+	fs, format, demo := newProjectsFlags()
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *format != "table" && *format != "json" && *format != "ids" {
+		return fail(usageErrorf("unknown format %q, use table, json or ids", *format))
+	}
+
+	session, err := openSession(*demo)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.Close()
+
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	projects, err := loadProjects(ctx, session.client)
+	if err != nil {
+		return fail(err)
+	}
+	tasks := allTasks(projects)
+	if query := strings.Join(fs.Args(), " "); query != "" {
+		tasks = searchTasks(tasks, query)
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(groupTasks(tasks))
+	case "ids":
+		for _, t := range tasks {
+			fmt.Println(t.Task.ID)
+		}
+	default:
+		err = writeTasksTable(os.Stdout, tasks)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if len(tasks) == 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// searchTasks fuzzy matches query against "Customer Project / Task" and
+// returns the matching tasks, best match first
+func searchTasks(tasks []taskRef, query string) []taskRef {
+	haystack := make([]string, len(tasks))
+	for i, t := range tasks {
+		haystack[i] = t.Project.Customer.Name + " " + t.Label()
+	}
+
+	var found []taskRef
+	for _, match := range fuzzy.Find(query, haystack) {
+		found = append(found, tasks[match.Index])
+	}
+	return found
+}
+
+// groupTasks rebuilds the projects that tasks belong to, keeping only
+// those tasks, in order of first appearance
+func groupTasks(tasks []taskRef) []types.Project {
+	projects := []types.Project{}
+	index := map[int]int{}
+	for _, t := range tasks {
+		i, ok := index[t.Project.ID]
+		if !ok {
+			i = len(projects)
+			index[t.Project.ID] = i
+			project := t.Project
+			project.Tasks = nil
+			projects = append(projects, project)
+		}
+		projects[i].Tasks = append(projects[i].Tasks, t.Task)
+	}
+	return projects
+}
+
+func writeTasksTable(w io.Writer, tasks []taskRef) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK ID\tPROJECT ID\tCUSTOMER\tPROJECT / TASK\tBILLABLE\tBUDGET")
+	for _, t := range tasks {
+		billable := "no"
+		if t.Billable() {
+			billable = "yes"
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t%s\n",
+			t.Task.ID, t.Project.ID, t.Project.Customer.Name, t.Label(), billable, formatBudget(t.Project))
+	}
+	return tw.Flush()
+}
+
+func formatBudget(p types.Project) string {
+	if p.Budget == nil {
+		return "-"
+	}
+	return strings.TrimSpace(fmt.Sprintf("%.2f %s", *p.Budget, p.Currency))
+}
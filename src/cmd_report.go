@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/denwerk/moco/src/report"
+)
+
+func init() {
+	registerCommand(command{
+		name:    "report",
+		summary: "summarize hours of a week or month against the weekly target",
+		run:     runReport,
+		flags:   func(string) *flag.FlagSet { fs, _, _ := newReportFlags(); return fs },
+	})
+}
+
+// periodReport is a summary together with its target hours
+type periodReport struct {
+	report.Summary
+	Period       string  `json:"period"`
+	WeeklyTarget float64 `json:"weekly_target"`
+	Target       float64 `json:"target"`         // Expected for the whole period
+	TargetToDate float64 `json:"target_to_date"` // Expected up to today, if the period is ongoing
+	Balance      float64 `json:"balance"`        // Booked minus expected up to today
+}
+
+func newPeriodReport(summary report.Summary, period string, weeklyTarget float64, now time.Time) periodReport {
+	today := now.Format("2006-01-02")
+	r := periodReport{
+		Summary:      summary,
+		Period:       period,
+		WeeklyTarget: weeklyTarget,
+		Target:       report.Target(weeklyTarget, summary.From, summary.To),
+	}
+	switch {
+	case today < summary.From:
+		r.TargetToDate = 0
+	case today < summary.To:
+		r.TargetToDate = report.Target(weeklyTarget, summary.From, today)
+	default:
+		r.TargetToDate = r.Target
+	}
+	r.Balance = summary.Hours - r.TargetToDate
+	return r
+}
+
+// reportFlags are the flags of 'moco report'
+type reportFlags struct {
+	period *string
+	date   *string
+	target *string
+	format *string
+}
+
+func newReportFlags() (*flag.FlagSet, *reportFlags, *bool) {
+	fs, demo := newFlagSet("report", "[--period week|month] [--date <date>] [--target <hours>] [--format table|json]")
+	flags := &reportFlags{
+		period: fs.String("period", "week", "week or month"),
+		date:   fs.String("date", "today", "report the period containing this day"),
+		target: fs.String("target", "", "weekly target in hours (default MOCO_WEEKLY_TARGET or 40)"),
+		format: fs.String("format", "table", "output format: table or json"),
+	}
+	return fs, flags, demo
+}
+
+func runReport(args []string) int {
+	fs, flags, demo := newReportFlags()
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *flags.period != "week" && *flags.period != "month" {
+		return fail(usageErrorf("unknown period %q, use week or month", *flags.period))
+	}
+	if *flags.format != "table" && *flags.format != "json" {
+		return fail(usageErrorf("unknown format %q, use table or json", *flags.format))
+	}
+
+	now := time.Now()
+	day, err := parseDate(*flags.date, now)
+	if err != nil {
+		return fail(err)
+	}
+	anchor, _ := time.ParseInLocation("2006-01-02", day, now.Location())
+	r, _ := presetRange("this-"+*flags.period, anchor)
+
+	session, err := openSession(*demo)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.Close()
+
+	weeklyTarget := session.cfg.WeeklyTarget
+	if *flags.target != "" {
+		hours, err := parseHours(*flags.target)
+		if err != nil {
+			return fail(usageErrorf("invalid target: %v", err))
+		}
+		weeklyTarget = hours
+	}
+
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	entries, info, err := session.client.Activities(ctx, r.From, r.To)
+	if err != nil {
+		return fail(err)
+	}
+	if info.Truncated {
+		fmt.Fprintf(os.Stderr, "moco: list truncated, report covers %d of %d entries\n", info.Fetched, info.Total)
+	}
+
+	rep := newPeriodReport(report.Summarize(entries, r.From, r.To), *flags.period, weeklyTarget, now)
+	if *flags.format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rep)
+	} else {
+		err = writeReport(os.Stdout, rep)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func writeReport(w io.Writer, r periodReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	title := "Week"
+	if r.Period == "month" {
+		title = "Month"
+	}
+	fmt.Fprintf(tw, "%s %s - %s\n", title, r.From, r.To)
+
+	section := func(title string, groups []report.Group, label func(report.Group) string) {
+		fmt.Fprintf(tw, "\n%s\tHOURS\tBILLABLE\n", title)
+		for _, g := range groups {
+			fmt.Fprintf(tw, "  %s\t%.2f\t%.2f\n", label(g), g.Hours, g.Billable)
+		}
+	}
+	section("DAY", r.Days, func(g report.Group) string {
+		if day, err := time.Parse("2006-01-02", g.Key); err == nil {
+			return day.Format("Mon 2006-01-02")
+		}
+		return g.Key
+	})
+	name := func(g report.Group) string { return g.Name }
+	section("CUSTOMER", r.Customers, name)
+	section("PROJECT", r.Projects, name)
+	section("TASK", r.Tasks, name)
+
+	fmt.Fprintf(tw, "\nTotal\t%.2f\t%.2f\n", r.Hours, r.Billable)
+	fmt.Fprintf(tw, "Target\t%.2f\t(%.2fh per week)\n", r.Target, r.WeeklyTarget)
+	if r.TargetToDate != r.Target {
+		fmt.Fprintf(tw, "Target to date\t%.2f\t\n", r.TargetToDate)
+	}
+	fmt.Fprintf(tw, "Balance\t%+.2f\t\n", r.Balance)
+	return tw.Flush()
+}
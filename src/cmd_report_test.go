@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/denwerk/moco/src/report"
+)
+
+func TestReportPeriodBounds(t *testing.T) {
+	tests := []struct {
+		period string
+		day    string
+		from   string
+		to     string
+	}{
+		{"week", "2026-10-12", "2026-10-12", "2026-10-18"}, // Monday
+		{"week", "2026-10-15", "2026-10-12", "2026-10-18"},
+		{"week", "2026-10-18", "2026-10-12", "2026-10-18"}, // Sunday still belongs to the week before
+		{"week", "2026-12-31", "2026-12-28", "2027-01-03"},
+		{"month", "2026-10-01", "2026-10-01", "2026-10-31"},
+		{"month", "2026-10-31", "2026-10-01", "2026-10-31"},
+		{"month", "2026-11-15", "2026-11-01", "2026-11-30"},
+		{"month", "2028-02-10", "2028-02-01", "2028-02-29"},
+		{"month", "2026-12-31", "2026-12-01", "2026-12-31"},
+	}
+	for _, tt := range tests {
+		anchor, _ := time.ParseInLocation("2006-01-02", tt.day, time.Local)
+		r, ok := presetRange("this-"+tt.period, anchor)
+		if !ok || r.From != tt.from || r.To != tt.to {
+			t.Errorf("%s of %s = %s to %s, want %s to %s", tt.period, tt.day, r.From, r.To, tt.from, tt.to)
+		}
+	}
+}
+
+func TestNewPeriodReport(t *testing.T) {
+	summary := report.Summary{From: "2026-10-12", To: "2026-10-18", Hours: 20}
+	tests := []struct {
+		name         string
+		now          string
+		target       float64
+		targetToDate float64
+		balance      float64
+	}{
+		{"before the period", "2026-10-09", 40, 0, 20},
+		{"on the first day", "2026-10-12", 40, 8, 12},
+		{"midweek", "2026-10-14", 40, 24, -4},
+		{"on the weekend", "2026-10-17", 40, 40, -20},
+		{"after the period", "2026-10-20", 40, 40, -20},
+	}
+	for _, tt := range tests {
+		now, _ := time.ParseInLocation("2006-01-02", tt.now, time.Local)
+		r := newPeriodReport(summary, "week", 40, now.Add(15*time.Hour))
+		if r.Target != tt.target || r.TargetToDate != tt.targetToDate || r.Balance != tt.balance {
+			t.Errorf("%s: target %.2f, to date %.2f, balance %.2f, want %.2f, %.2f, %.2f",
+				tt.name, r.Target, r.TargetToDate, r.Balance, tt.target, tt.targetToDate, tt.balance)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/denwerk/moco/src/types"
+	"github.com/denwerk/moco/src/ui"
+)
+
+func init() {
+	registerCommand(command{
+		name:        "timer",
+		summary:     "start, stop or show the Moco timer (start|stop|toggle|status)",
+		run:         runTimer,
+		subcommands: []string{"start", "stop", "toggle", "status"},
+		flags:       timerFlags,
+	})
+}
+
+// defaultTimerFormat is used by 'timer status' unless MOCO_TIMER_FORMAT or
+// --format say otherwise
+const defaultTimerFormat = "⏱ {{.Label}} {{.Clock}}"
+
+// timerStatus holds the fields available to status templates
+type timerStatus struct {
+	EntryID     int
+	Project     string
+	Task        string
+	Label       string // "Project / Task"
+	Description string
+	StartedAt   time.Time
+	Elapsed     string  // Time on the entry as h:mm:ss
+	Clock       string  // Time on the entry as h:mm
+	Hours       float64 // Time on the entry in decimal hours
+}
+
+func newTimerStatus(state *TimerState, now time.Time) timerStatus {
+	elapsed := state.Elapsed(now)
+	minutes := int(elapsed / time.Minute)
+	return timerStatus{
+		EntryID:     state.EntryID,
+		Project:     state.Project,
+		Task:        state.Task,
+		Label:       state.Project + " / " + state.Task,
+		Description: state.Description,
+		StartedAt:   state.StartedAt,
+		Elapsed:     ui.FormatElapsed(elapsed),
+		Clock:       fmt.Sprintf("%d:%02d", minutes/60, minutes%60),
+		Hours:       elapsed.Hours(),
+	}
+}
+
+func runTimer(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: moco timer start|stop|toggle|status [flags]")
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "start":
+		return runTimerStart(args[1:])
+	case "stop":
+		return runTimerStop(args[1:])
+	case "toggle":
+		return runTimerToggle(args[1:])
+	case "status":
+		return runTimerStatus(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "moco timer: unknown subcommand %q, use start, stop, toggle or status\n", args[0])
+	return exitUsage
+}
+
+// timerFlags returns the flag set of a timer subcommand, for completion
+func timerFlags(sub string) *flag.FlagSet {
+	switch sub {
+	case "start", "toggle":
+		fs, _, _ := newTimerStartFlags(sub)
+		return fs
+	case "status":
+		fs, _, _ := newTimerStatusFlags()
+		return fs
+	}
+	fs, _ := newFlagSet("timer "+sub, "")
+	return fs
+}
+
+// timerStartFlags are the flags of 'timer start' and 'timer toggle'
+type timerStartFlags struct {
+	task *string
+	desc *string
+}
+
+func newTimerStartFlags(name string) (*flag.FlagSet, *timerStartFlags, *bool) {
+	fs, demo := newFlagSet("timer "+name, "[--task <id|name>] [--desc <text>]")
+	flags := &timerStartFlags{
+		task: fs.String("task", "", "task ID or (part of) \"Project / Task\" (default the last task used)"),
+		desc: fs.String("desc", "", "description"),
+	}
+	return fs, flags, demo
+}
+
+func runTimerStart(args []string) int {
+	fs, flags, demo := newTimerStartFlags("start")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "moco timer start: unexpected arguments: %v\n", fs.Args())
+		return exitUsage
+	}
+
+	session, err := openSession(*demo)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.Close()
+
+	ctx, cancel := cliContext()
+	defer cancel()
+	return startTimer(ctx, session, flags)
+}
+
+func runTimerStop(args []string) int {
+	fs, demo := newFlagSet("timer stop", "")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	session, err := openSession(*demo)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.Close()
+
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	running, err := findRunningTimer(ctx, session)
+	if err != nil {
+		return fail(err)
+	}
+	if running == nil {
+		fmt.Println("No timer running")
+		return exitOK
+	}
+	return stopTimer(ctx, session, running)
+}
+
+func runTimerToggle(args []string) int {
+	fs, flags, demo := newTimerStartFlags("toggle")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "moco timer toggle: unexpected arguments: %v\n", fs.Args())
+		return exitUsage
+	}
+
+	session, err := openSession(*demo)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.Close()
+
+	ctx, cancel := cliContext()
+	defer cancel()
+
+	running, err := findRunningTimer(ctx, session)
+	if err != nil {
+		return fail(err)
+	}
+	if running != nil {
+		return stopTimer(ctx, session, running)
+	}
+	return startTimer(ctx, session, flags)
+}
+
+// timerStatusFlags are the flags of 'timer status'
+type timerStatusFlags struct {
+	format  *string
+	idle    *string
+	refresh *bool
+}
+
+func newTimerStatusFlags() (*flag.FlagSet, *timerStatusFlags, *bool) {
+	fs, demo := newFlagSet("timer status", "[--format <template>] [--idle <text>] [--refresh]")
+	flags := &timerStatusFlags{
+		format:  fs.String("format", "", "Go template with .Label, .Project, .Task, .Description, .Elapsed, .Clock, .Hours, .StartedAt, .EntryID (default MOCO_TIMER_FORMAT or \""+defaultTimerFormat+"\")"),
+		idle:    fs.String("idle", "", "text to print when no timer is running"),
+		refresh: fs.Bool("refresh", false, "ask Moco for the running timer and update the state file"),
+	}
+	return fs, flags, demo
+}
+
+// runTimerStatus prints the timer from the state file. It doesn't load the
+// configuration or call the API unless --refresh is given, so it is cheap
+// enough to run from a prompt every second. Without a running timer it
+// prints --idle and exits with 1.
+func runTimerStatus(args []string) int {
+	fs, flags, demo := newTimerStatusFlags()
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	stateProfile = selectedProfile()
+
+	if *flags.format == "" {
+		*flags.format = os.Getenv("MOCO_TIMER_FORMAT")
+	}
+	if *flags.format == "" {
+		*flags.format = defaultTimerFormat
+	}
+	tmpl, err := template.New("status").Parse(*flags.format)
+	if err != nil {
+		return fail(usageErrorf("invalid format: %v", err))
+	}
+
+	if *flags.refresh {
+		session, err := openSession(*demo)
+		if err != nil {
+			return fail(err)
+		}
+		ctx, cancel := cliContext()
+		_, err = findRunningTimer(ctx, session)
+		cancel()
+		session.Close()
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	state, err := LoadTimerState()
+	if err != nil {
+		return fail(err)
+	}
+	if state == nil {
+		if *flags.idle != "" {
+			fmt.Println(*flags.idle)
+		}
+		return exitError
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, newTimerStatus(state, time.Now())); err != nil {
+		return fail(usageErrorf("invalid format: %v", err))
+	}
+	fmt.Println(out.String())
+	return exitOK
+}
+
+// findRunningTimer asks Moco for the running timer and records it in the
+// state file. Timers started before midnight are still found.
+func findRunningTimer(ctx context.Context, session *cliSession) (*types.TimeEntry, error) {
+	now := time.Now()
+	running, err := session.client.RunningTimer(ctx,
+		now.AddDate(0, 0, -1).Format("2006-01-02"), now.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	if err := syncTimerState(running); err != nil {
+		fmt.Fprintf(os.Stderr, "moco: %v\n", err)
+	}
+	return running, nil
+}
+
+// startTimer books an empty entry for today on the task and starts its timer
+func startTimer(ctx context.Context, session *cliSession, flags *timerStartFlags) int {
+	projects, err := loadProjects(ctx, session.client)
+	if err != nil {
+		return fail(err)
+	}
+
+	query := *flags.task
+	if query == "" {
+		last, err := LoadLastTask()
+		if err != nil {
+			return fail(err)
+		}
+		if last == nil || last.TaskID == 0 {
+			return fail(usageErrorf("no last task to continue, pass --task"))
+		}
+		query = strconv.Itoa(last.TaskID)
+	}
+	t, err := findTask(projects, query)
+	if err != nil {
+		return fail(err)
+	}
+
+	ref, err := resolveTicket(session.cfg.TicketRules, *flags.desc, "")
+	if err != nil {
+		return fail(err)
+	}
+	billable := t.Billable()
+	started, err := session.client.StartNewTimer(ctx, types.TimeEntry{
+		Date:          time.Now().Format("2006-01-02"),
+		ProjectID:     t.Project.ID,
+		TaskID:        t.Task.ID,
+		Description:   *flags.desc,
+		Billable:      &billable,
+		RemoteService: ref.Service,
+		RemoteID:      ref.ID,
+		RemoteURL:     ref.URL,
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := SaveTimerState(newTimerState(*started)); err != nil {
+		fmt.Fprintf(os.Stderr, "moco: %v\n", err)
+	}
+	if *flags.task != "" {
+		if err := SaveLastTask(LastTask{ProjectID: t.Project.ID, TaskID: t.Task.ID, TaskTitle: t.Task.Name}); err != nil {
+			fmt.Fprintf(os.Stderr, "moco: %v\n", err)
+		}
+	}
+
+	fmt.Printf("Timer started on %s (#%d)\n", t.Label(), started.ID)
+	return exitOK
+}
+
+func stopTimer(ctx context.Context, session *cliSession, running *types.TimeEntry) int {
+	stopped, err := session.client.StopTimer(ctx, running.ID)
+	if err != nil {
+		return fail(err)
+	}
+	if err := SaveTimerState(nil); err != nil {
+		fmt.Fprintf(os.Stderr, "moco: %v\n", err)
+	}
+
+	fmt.Printf("Timer stopped on %s / %s, %.2fh booked (#%d)\n",
+		running.Project.Name, running.Task.Name, stopped.Hours, stopped.ID)
+	return exitOK
+}
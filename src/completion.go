@@ -0,0 +1,290 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerCommand(command{
+		name:        "completion",
+		summary:     "print a shell completion script (bash|zsh|fish)",
+		run:         runCompletion,
+		subcommands: []string{"bash", "zsh", "fish"},
+	})
+	registerCommand(command{
+		name:   "__complete",
+		run:    runComplete,
+		hidden: true,
+	})
+}
+
+// completionScripts hand the words typed so far to 'moco __complete',
+// which prints one candidate per line
+var completionScripts = map[string]string{
+	"bash": `# bash completion for moco, load with: source <(moco completion bash)
+_moco() {
+    local IFS=$'\n' candidate
+    COMPREPLY=()
+    for candidate in $(moco __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null); do
+        COMPREPLY+=("$(printf '%q' "$candidate")")
+    done
+}
+complete -o default -F _moco moco
+`,
+	"zsh": `#compdef moco
+# zsh completion for moco, load with: source <(moco completion zsh)
+_moco() {
+    local -a candidates
+    candidates=("${(@f)$(moco __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if (( ${#candidates} )) && [[ -n ${candidates[1]} ]]; then
+        compadd -U -- "${candidates[@]}"
+    else
+        _files
+    fi
+}
+compdef _moco moco
+`,
+	"fish": `# fish completion for moco, load with: moco completion fish | source
+function __moco_complete
+    set -l words (commandline -opc)
+    set -e words[1]
+    moco __complete $words (commandline -ct) 2>/dev/null
+end
+complete -c moco -f -a '(__moco_complete)'
+`,
+}
+
+func runCompletion(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: moco completion bash|zsh|fish")
+		return exitUsage
+	}
+	script, ok := completionScripts[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "moco completion: unsupported shell %q, use bash, zsh or fish\n", args[0])
+		return exitUsage
+	}
+	fmt.Print(script)
+	return exitOK
+}
+
+// runComplete prints the completions for the words after "moco", the last
+// of which is the one being completed
+func runComplete(args []string) int {
+	for _, candidate := range complete(args) {
+		fmt.Println(candidate)
+	}
+	return exitOK
+}
+
+// flagValues completes the values of flags, by flag name. Commands whose
+// formats differ have entries of the form "command.flag".
+var flagValues = map[string]func() []string{
+	"task":            taskLabels,
+	"date":            dateWords,
+	"from":            dateWords,
+	"to":              dateWords,
+	"period":          words("week", "month"),
+	"locale":          words("de", "en"),
+	"profile":         profileNames,
+	"ls.format":       func() []string { return outputFormats },
+	"report.format":   words("table", "json"),
+	"projects.format": words("table", "json", "ids"),
+	"export.format":   words("csv", "json", "ndjson", "ics"),
+}
+
+// positionalValues completes arguments that aren't flags, by command
+var positionalValues = map[string]func() []string{
+	"ls":     func() []string { return rangePresets },
+	"export": func() []string { return rangePresets },
+}
+
+func complete(args []string) []string {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	words := make([]string, len(args))
+	for i, arg := range args {
+		words[i] = unescapeWord(arg)
+	}
+	current := words[len(words)-1]
+	before := words[:len(words)-1]
+	// bash splits --flag=value into "--flag", "=", "value"
+	if n := len(before); n >= 2 && before[n-1] == "=" {
+		before = before[:n-1]
+	}
+
+	if len(before) == 0 {
+		var names []string
+		for name, cmd := range commands {
+			if !cmd.hidden {
+				names = append(names, name)
+			}
+		}
+		return filterCandidates(append(names, "help", "--demo", "--profile"), current)
+	}
+
+	cmd, ok := commands[before[0]]
+	if !ok {
+		return nil
+	}
+	if len(cmd.subcommands) > 0 && len(before) == 1 {
+		return filterCandidates(cmd.subcommands, current)
+	}
+
+	fs := commandFlags(cmd, before[1:])
+	if fs == nil {
+		return nil
+	}
+	// Task labels come from the cache of the profile being completed for
+	configFlags.File = configFlagValue(before[1:], "config")
+	configFlags.Profile = configFlagValue(before[1:], "profile")
+
+	// zsh and fish pass --flag=value as one word
+	if strings.HasPrefix(current, "-") && strings.Contains(current, "=") {
+		flagName, value, _ := strings.Cut(current, "=")
+		var candidates []string
+		for _, c := range complete(append(append(before[:len(before):len(before)], flagName), value)) {
+			candidates = append(candidates, flagName+"="+c)
+		}
+		return candidates
+	}
+
+	// The value of a flag
+	if prev := before[len(before)-1]; strings.HasPrefix(prev, "-") && !strings.Contains(prev, "=") {
+		name := strings.TrimLeft(prev, "-")
+		if f := fs.Lookup(name); f != nil && !isBoolFlag(f) {
+			values := flagValues[cmd.name+"."+name]
+			if values == nil {
+				values = flagValues[name]
+			}
+			if values == nil {
+				return nil
+			}
+			return filterCandidates(values(), current)
+		}
+	}
+
+	if strings.HasPrefix(current, "-") {
+		var names []string
+		fs.VisitAll(func(f *flag.Flag) { names = append(names, "--"+f.Name) })
+		return filterCandidates(names, current)
+	}
+	if values := positionalValues[cmd.name]; values != nil {
+		return filterCandidates(values(), current)
+	}
+	return nil
+}
+
+// commandFlags returns the flag set of a command. Commands with
+// subcommands need the subcommand as first argument.
+func commandFlags(cmd command, args []string) *flag.FlagSet {
+	if cmd.flags == nil {
+		return nil
+	}
+	sub := ""
+	if len(cmd.subcommands) > 0 {
+		if len(args) == 0 || !containsString(cmd.subcommands, args[0]) {
+			return nil
+		}
+		sub = args[0]
+	}
+	return cmd.flags(sub)
+}
+
+// configFlagValue returns the value given to a config flag such as
+// --profile in the words typed so far, or "" if there is none
+func configFlagValue(words []string, name string) string {
+	value := ""
+	for i, word := range words {
+		flagName, flagValue, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		if !strings.HasPrefix(word, "-") || flagName != name {
+			continue
+		}
+		if hasValue {
+			value = flagValue
+		} else if i+1 < len(words) {
+			value = words[i+1]
+		}
+	}
+	return value
+}
+
+// unescapeWord removes the quoting the shell hands over with the word
+// being completed, so "Foo\ Bar" and "'Foo Bar" both become "Foo Bar"
+func unescapeWord(word string) string {
+	word = strings.TrimLeft(word, `"'`)
+	var b strings.Builder
+	escaped := false
+	for _, r := range word {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// filterCandidates keeps the candidates starting with prefix, ignoring
+// case. If none does, those containing all words of prefix are kept, so
+// "dev acme" still finds "ACME Website / Development".
+func filterCandidates(candidates []string, prefix string) []string {
+	lower := strings.ToLower(prefix)
+	var matched []string
+	for _, c := range candidates {
+		if strings.HasPrefix(strings.ToLower(c), lower) {
+			matched = append(matched, c)
+		}
+	}
+	if len(matched) == 0 && strings.TrimSpace(prefix) != "" {
+		for _, c := range candidates {
+			if containsAllWords(strings.ToLower(c), lower) {
+				matched = append(matched, c)
+			}
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+// taskLabels lists the tasks of the cached projects. Completion must be
+// fast and work offline, so it never calls the API.
+func taskLabels() []string {
+	stateProfile = selectedProfile()
+	cache, err := LoadProjectCache("")
+	if err != nil || cache == nil {
+		return nil
+	}
+	var labels []string
+	for _, t := range allTasks(cache.Projects) {
+		labels = append(labels, t.Label())
+	}
+	return labels
+}
+
+func profileNames() []string {
+	file, err := readConfigFile(configFlags.File)
+	if err != nil || file == nil {
+		return nil
+	}
+	return file.ProfileNames()
+}
+
+func dateWords() []string {
+	return []string{"today", "yesterday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+}
+
+func words(values ...string) func() []string {
+	return func() []string { return values }
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+func TestCompleteCommandsAndFlags(t *testing.T) {
+	isolate(t)
+
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"ex"}, []string{"export"}},
+		{[]string{"timer", "st"}, []string{"start", "status", "stop"}},
+		{[]string{"add", "--ta"}, []string{"--tag", "--task"}},
+		{[]string{"add", "--hours", "1", "--qu"}, []string{"--quiet"}},
+		{[]string{"timer", "status", "--re"}, []string{"--refresh"}},
+		{[]string{"auth", "login", "--no"}, []string{"--no-verify"}},
+		{[]string{"ls", "--format", "j"}, []string{"json"}},
+		{[]string{"ls", "--format=j"}, []string{"--format=json"}},
+		{[]string{"export", "--format", "i"}, []string{"ics"}},
+		{[]string{"ls", "this-w"}, []string{"this-week"}},
+		{[]string{"timer", "nope", "--"}, nil},
+	}
+	for _, tt := range tests {
+		if got := complete(tt.words); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("complete(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteTaskLabels(t *testing.T) {
+	isolate(t)
+	saveCache := func(profile, project string) {
+		stateProfile = profile
+		err := SaveProjectCache(&ProjectCache{Projects: []types.Project{
+			{ID: 1, Name: project, Tasks: []types.Task{{ID: 2, Name: "Code Review"}}},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	saveCache("", "Foo Bar")
+	saveCache("work", "Globex")
+	stateProfile = ""
+
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"add", "--task", `Foo\ B`}, []string{"Foo Bar / Code Review"}},
+		{[]string{"add", "--task", `"Foo B`}, []string{"Foo Bar / Code Review"}},
+		{[]string{"add", "--profile", "work", "--task", ""}, []string{"Globex / Code Review"}},
+		{[]string{"add", "--task", "", "--profile=work", "--task", "glo"}, []string{"Globex / Code Review"}},
+	}
+	for _, tt := range tests {
+		if got := complete(tt.words); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("complete(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
@@ -1,37 +1,297 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/denwerk/moco/src/api"
+	"github.com/denwerk/moco/src/ticket"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	MocoDomain string
-	MocoAPIKey string
+	MocoDomain   string // Account name for display: the mocoapp.com subdomain or the API host
+	MocoAPIKey   string
+	MocoBaseURL  string // API root, derived from MOCO_DOMAIN unless MOCO_BASE_URL is set
+	TicketRules  []ticket.Rule
+	WeeklyTarget float64 // Hours expected per week, for reports
+
+	CredentialTTL time.Duration // How long an unlocked API key stays with the agent
+
+	Keys   map[string][]string // Key bindings from the config file, see newKeyMap
+	Theme  string              // Theme name or "auto", see loadTheme
+	Colors map[string]string   // Theme colours from the config file
+
+	File     string            // Config file the settings were merged from, empty without one
+	Profile  string            // Selected profile of the config file, empty without one
+	Profiles []string          // Profiles defined in the config file, sorted
+	Sources  map[string]string // Where each setting came from, by environment variable
+}
+
+// DefaultWeeklyTarget is used when MOCO_WEEKLY_TARGET is not set
+const DefaultWeeklyTarget = 40.0
+
+// settings are the environment variables LoadConfig understands. In the
+// config file they are written in lower case without the MOCO_ prefix, e.g.
+// api_key for MOCO_API_KEY.
+var settings = []string{
+	"MOCO_DOMAIN",
+	"MOCO_API_KEY",
+	"MOCO_BASE_URL",
+	"MOCO_WEEKLY_TARGET",
+	"MOCO_JIRA_URL",
+	"MOCO_GITHUB_REPO",
+	"MOCO_TICKET_PATTERNS",
+	"MOCO_CREDENTIAL_TTL",
+	"MOCO_THEME",
+}
+
+// settingEnv maps a config file key to its environment variable
+func settingEnv(key string) (string, bool) {
+	env := "MOCO_" + strings.ToUpper(key)
+	return env, containsString(settings, env)
 }
 
+// ConfigFlags are the command-line flags that take part in loading the
+// configuration. Every flag set registers them, see registerConfigFlags.
+type ConfigFlags struct {
+	File    string
+	Profile string
+	Domain  string
+	BaseURL string
+	APIKey  string // Not a flag: lets 'moco auth login' try a key before storing it
+}
+
+// configFlags holds the flags of the running command
+var configFlags ConfigFlags
+
+func registerConfigFlags(fs *flag.FlagSet) {
+	fs.StringVar(&configFlags.File, "config", "", "config file (default $MOCO_CONFIG_FILE or ~/.config/moco/config.toml)")
+	fs.StringVar(&configFlags.Profile, "profile", "", "profile of the config file to use (default $MOCO_PROFILE)")
+	fs.StringVar(&configFlags.Domain, "domain", "", "Moco domain, overrides MOCO_DOMAIN")
+	fs.StringVar(&configFlags.BaseURL, "base-url", "", "API root, overrides MOCO_BASE_URL")
+}
+
+// configLayer is one source of settings, keyed by environment variable
+type configLayer struct {
+	source string
+	values map[string]string
+}
+
+// LoadConfig loads the configuration selected by the command-line flags and
+// points the local state at the selected profile, see stateProfile
 func LoadConfig() (*Config, error) {
-	// Load .env file
-	err := godotenv.Load()
+	cfg, err := loadConfig(configFlags)
+	if err != nil {
+		return nil, err
+	}
+	stateProfile = cfg.Profile
+	return cfg, nil
+}
+
+// loadConfig merges the settings from, lowest priority first: a .env file in
+// the working directory, the top level of the config file, the selected
+// profile, environment variables and command-line flags
+func loadConfig(flags ConfigFlags) (*Config, error) {
+	file, err := readConfigFile(flags.File)
 	if err != nil {
-		return nil, fmt.Errorf("error loading .env file: %v", err)
+		return nil, err
+	}
+
+	dotenv, err := godotenv.Read()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, &ConfigError{fmt.Sprintf("error reading .env file: %v", err)}
+	}
+	layers := []configLayer{{".env", dotenv}}
+
+	cfg := &Config{Profile: chooseProfile(flags.Profile, file)}
+	if file != nil {
+		cfg.File = file.Path
+		cfg.Profiles = file.ProfileNames()
+		cfg.Keys = file.Keys
+		cfg.Colors = file.Colors
+		layers = append(layers, configLayer{file.Path, file.Settings})
 	}
+	if cfg.Profile != "" {
+		if file == nil {
+			return nil, &ConfigError{fmt.Sprintf("profile %q selected, but there is no config file", cfg.Profile)}
+		}
+		profile, ok := file.Profiles[cfg.Profile]
+		if !ok {
+			return nil, &ConfigError{fmt.Sprintf("profile %q is not defined in %s (profiles: %s)",
+				cfg.Profile, file.Path, strings.Join(cfg.Profiles, ", "))}
+		}
+		layers = append(layers, configLayer{fmt.Sprintf("profile %s in %s", cfg.Profile, file.Path), profile})
+	}
+
+	environment := map[string]string{}
+	for _, name := range settings {
+		environment[name] = os.Getenv(name)
+	}
+	layers = append(layers,
+		configLayer{"environment", environment},
+		configLayer{"--domain flag", map[string]string{"MOCO_DOMAIN": flags.Domain}},
+		configLayer{"--base-url flag", map[string]string{"MOCO_BASE_URL": flags.BaseURL}},
+		configLayer{"moco auth login", map[string]string{"MOCO_API_KEY": flags.APIKey}},
+	)
+
+	values := map[string]string{}
+	cfg.Sources = map[string]string{}
+	for _, layer := range layers {
+		for name, value := range layer.values {
+			if value != "" {
+				values[name] = value
+				cfg.Sources[name] = layer.source
+			}
+		}
+	}
+
+	cfg.Theme = values["MOCO_THEME"]
 
-	cfg := &Config{
-		MocoDomain: os.Getenv("MOCO_DOMAIN"),
-		MocoAPIKey: os.Getenv("MOCO_API_KEY"),
+	cfg.CredentialTTL = DefaultCredentialTTL
+	if ttl := values["MOCO_CREDENTIAL_TTL"]; ttl != "" {
+		duration, err := time.ParseDuration(ttl)
+		if err != nil || duration <= 0 {
+			return nil, settingError("MOCO_CREDENTIAL_TTL", cfg.Sources, fmt.Errorf("%q is not a duration like 8h or 30m", ttl))
+		}
+		cfg.CredentialTTL = duration
 	}
 
-	if cfg.MocoDomain == "" || cfg.MocoAPIKey == "" {
-		return nil, ErrMissingEnvVars
+	if values["MOCO_DOMAIN"] == "" && values["MOCO_BASE_URL"] == "" {
+		return nil, missingSettings(cfg)
+	}
+	if err := resolveAccount(cfg, values); err != nil {
+		return nil, err
+	}
+
+	// The credential store is only unlocked if no other source has the key
+	if values["MOCO_API_KEY"] == "" {
+		dir, err := profileDir(cfg.Profile)
+		if err != nil {
+			return nil, err
+		}
+		apiKey, err := unlockAPIKey(dir, cfg.CredentialTTL)
+		if err != nil {
+			return nil, err
+		}
+		if apiKey == "" {
+			return nil, missingSettings(cfg)
+		}
+		values["MOCO_API_KEY"] = apiKey
+		cfg.Sources["MOCO_API_KEY"] = "credential store"
+	}
+	cfg.MocoAPIKey = values["MOCO_API_KEY"]
+
+	rules, err := loadTicketRules(values, cfg.Sources)
+	if err != nil {
+		return nil, err
+	}
+	cfg.TicketRules = rules
+
+	cfg.WeeklyTarget = DefaultWeeklyTarget
+	if target := values["MOCO_WEEKLY_TARGET"]; target != "" {
+		hours, err := parseHours(target)
+		if err != nil {
+			return nil, settingError("MOCO_WEEKLY_TARGET", cfg.Sources, err)
+		}
+		cfg.WeeklyTarget = hours
 	}
 
 	return cfg, nil
 }
 
-var ErrMissingEnvVars = &ConfigError{"MOCO_DOMAIN and MOCO_API_KEY environment variables must be set"}
+// missingSettings explains why the domain or API key are missing
+func missingSettings(cfg *Config) error {
+	if cfg.Profile == "" && len(cfg.Profiles) > 0 {
+		return &ConfigError{fmt.Sprintf("no profile selected: choose one of %s with --profile or MOCO_PROFILE, or set profile in %s",
+			strings.Join(cfg.Profiles, ", "), cfg.File)}
+	}
+	return ErrMissingEnvVars
+}
+
+// resolveAccount normalises MOCO_DOMAIN and MOCO_BASE_URL, which may each
+// be a bare subdomain, a host or a URL, into the account name and API root.
+// MOCO_BASE_URL decides where requests go; MOCO_DOMAIN, if also set, only
+// names the account.
+func resolveAccount(cfg *Config, values map[string]string) error {
+	var domain, base api.Account
+	var err error
+	if raw := values["MOCO_DOMAIN"]; raw != "" {
+		if domain, err = api.ParseAccount(raw); err != nil {
+			return settingError("MOCO_DOMAIN", cfg.Sources, err)
+		}
+	}
+	if raw := values["MOCO_BASE_URL"]; raw != "" {
+		if base, err = api.ParseAccount(raw); err != nil {
+			return settingError("MOCO_BASE_URL", cfg.Sources, err)
+		}
+	}
+
+	cfg.MocoDomain, cfg.MocoBaseURL = domain.Name, domain.BaseURL
+	if base.BaseURL != "" {
+		cfg.MocoBaseURL = base.BaseURL
+		if cfg.MocoDomain == "" {
+			cfg.MocoDomain = base.Name
+		}
+	}
+	return nil
+}
+
+// chooseProfile picks the profile named by the flag, MOCO_PROFILE or the
+// config file, in that order
+func chooseProfile(flagValue string, file *configFile) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if name := os.Getenv("MOCO_PROFILE"); name != "" {
+		return name
+	}
+	if file != nil {
+		return file.Default
+	}
+	return ""
+}
+
+// selectedProfile works out the profile like LoadConfig without loading the
+// rest of the configuration. Commands that only read local state use it to
+// find the profile's state directory.
+func selectedProfile() string {
+	if configFlags.Profile == "" && os.Getenv("MOCO_PROFILE") == "" {
+		file, err := readConfigFile(configFlags.File)
+		if err != nil {
+			return ""
+		}
+		return chooseProfile("", file)
+	}
+	return chooseProfile(configFlags.Profile, nil)
+}
+
+// loadTicketRules builds the ticket detection rules. Custom rules from
+// MOCO_TICKET_PATTERNS are tried before the built-in Jira and GitHub ones.
+func loadTicketRules(values, sources map[string]string) ([]ticket.Rule, error) {
+	rules, err := ticket.ParseRules(values["MOCO_TICKET_PATTERNS"])
+	if err != nil {
+		return nil, settingError("MOCO_TICKET_PATTERNS", sources, err)
+	}
+	if jiraURL := values["MOCO_JIRA_URL"]; jiraURL != "" {
+		rules = append(rules, ticket.JiraRule(jiraURL))
+	}
+	if repo := values["MOCO_GITHUB_REPO"]; repo != "" {
+		rules = append(rules, ticket.GitHubRule(repo))
+	}
+	return rules, nil
+}
+
+// settingError reports an invalid setting along with where it was set
+func settingError(name string, sources map[string]string, err error) error {
+	return &ConfigError{fmt.Sprintf("%s (from %s): %v", name, sources[name], err)}
+}
+
+var ErrMissingEnvVars = &ConfigError{"MOCO_DOMAIN and MOCO_API_KEY must be set, in the config file (domain, api_key), .env or the environment; or store the key with 'moco auth login'"}
 
 type ConfigError struct {
 	message string
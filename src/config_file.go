@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is a parsed config file. Settings are keyed by their
+// environment variable, see settings.
+type configFile struct {
+	Path     string
+	Default  string                       // Profile used when none is selected
+	Settings map[string]string            // Top-level settings, shared by all profiles
+	Profiles map[string]map[string]string // Settings of each named profile
+	Keys     map[string][]string          // Key bindings by action, see keyActions
+	Colors   map[string]string            // Theme colours by role, see ui.Theme
+}
+
+// configFileNames are looked for, in this order, in the config directory
+var configFileNames = []string{"config.toml", "config.yaml", "config.yml"}
+
+// profileNamePattern keeps profile names usable as directory names
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ProfileNames returns the names of the profiles, sorted
+func (f *configFile) ProfileNames() []string {
+	var names []string
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// userConfigDir is $XDG_CONFIG_HOME/moco, or ~/.config/moco
+func userConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "moco"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".config", "moco"), nil
+}
+
+// configFilePath finds the config file: path if given, else MOCO_CONFIG_FILE,
+// else one of configFileNames in userConfigDir. It returns "" when there is
+// no config file, which is fine; the settings may all come from elsewhere.
+func configFilePath(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv("MOCO_CONFIG_FILE")
+	}
+	if path != "" {
+		return path, nil
+	}
+
+	dir, err := userConfigDir()
+	if err != nil {
+		return "", err
+	}
+	var found []string
+	for _, name := range configFileNames {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			found = append(found, filepath.Join(dir, name))
+		}
+	}
+	if len(found) > 1 {
+		return "", &ConfigError{fmt.Sprintf("found both %s and %s, remove one of them", found[0], found[1])}
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0], nil
+}
+
+// readConfigFile reads and parses the config file, see configFilePath. It
+// returns nil without error when there is none.
+func readConfigFile(path string) (*configFile, error) {
+	path, err := configFilePath(path)
+	if err != nil || path == "" {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ConfigError{fmt.Sprintf("error reading config file: %v", err)}
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		err = toml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, &ConfigError{fmt.Sprintf("%s: %v", path, err)}
+	}
+
+	file, err := parseConfigFile(path, raw)
+	if err != nil {
+		return nil, &ConfigError{fmt.Sprintf("%s: %v", path, err)}
+	}
+	return file, nil
+}
+
+func parseConfigFile(path string, raw map[string]interface{}) (*configFile, error) {
+	file := &configFile{Path: path, Profiles: map[string]map[string]string{}}
+
+	settings, err := parseSettings(raw, "profile", "profiles", "keys", "colors")
+	if err != nil {
+		return nil, err
+	}
+	file.Settings = settings
+
+	if value, ok := raw["profile"]; ok {
+		name, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("profile must be the name of a profile")
+		}
+		file.Default = name
+	}
+
+	if value, ok := raw["profiles"]; ok {
+		profiles, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("profiles must be a table of named profiles")
+		}
+		for name, value := range profiles {
+			if !profileNamePattern.MatchString(name) {
+				return nil, fmt.Errorf("profile name %q may only contain letters, digits, '-' and '_'", name)
+			}
+			table, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("profile %s must be a table of settings", name)
+			}
+			for _, key := range []string{"keys", "colors"} {
+				if _, ok := table[key]; ok {
+					return nil, fmt.Errorf("profile %s: %s apply to all profiles, set them at the top level", name, key)
+				}
+			}
+			settings, err := parseSettings(table)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s: %v", name, err)
+			}
+			file.Profiles[name] = settings
+		}
+	}
+
+	if value, ok := raw["keys"]; ok {
+		keys, err := parseKeys(value)
+		if err != nil {
+			return nil, err
+		}
+		file.Keys = keys
+	}
+
+	if value, ok := raw["colors"]; ok {
+		colors, err := parseColors(value)
+		if err != nil {
+			return nil, err
+		}
+		file.Colors = colors
+	}
+
+	if _, ok := file.Profiles[file.Default]; file.Default != "" && !ok {
+		return nil, fmt.Errorf("profile %q is not defined", file.Default)
+	}
+	return file, nil
+}
+
+// parseSettings converts a table of the config file into settings keyed by
+// environment variable. Keys listed in skip are left to the caller.
+func parseSettings(table map[string]interface{}, skip ...string) (map[string]string, error) {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := map[string]string{}
+	for _, key := range keys {
+		if containsString(skip, key) {
+			continue
+		}
+		env, ok := settingEnv(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+		switch value := table[key].(type) {
+		case string:
+			values[env] = value
+		case int:
+			values[env] = strconv.Itoa(value)
+		case int64:
+			values[env] = strconv.FormatInt(value, 10)
+		case float64:
+			values[env] = strconv.FormatFloat(value, 'f', -1, 64)
+		default:
+			return nil, fmt.Errorf("%s must be a string or a number", key)
+		}
+	}
+	return values, nil
+}
+
+// parseKeys converts the keys table, which maps actions to a key or a list
+// of keys. Whether they make sense is up to newKeyMap.
+func parseKeys(value interface{}) (map[string][]string, error) {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("keys must be a table of actions")
+	}
+	keys := map[string][]string{}
+	for action, value := range table {
+		switch value := value.(type) {
+		case string:
+			keys[action] = []string{value}
+		case []interface{}:
+			list := []string{}
+			for _, item := range value {
+				key, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("keys.%s must be a key or a list of keys", action)
+				}
+				list = append(list, key)
+			}
+			keys[action] = list
+		default:
+			return nil, fmt.Errorf("keys.%s must be a key or a list of keys", action)
+		}
+	}
+	return keys, nil
+}
+
+// parseColors converts the colors table, which maps roles to an ANSI number
+// or a hex colour. Whether they make sense is up to ui.Theme.WithColors.
+func parseColors(value interface{}) (map[string]string, error) {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("colors must be a table of colours")
+	}
+	colors := map[string]string{}
+	for role, value := range table {
+		switch value := value.(type) {
+		case string:
+			colors[role] = value
+		case int:
+			colors[role] = strconv.Itoa(value)
+		case int64:
+			colors[role] = strconv.FormatInt(value, 10)
+		default:
+			return nil, fmt.Errorf("colors.%s must be an ANSI number or a hex colour", role)
+		}
+	}
+	return colors, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes the config file that is found by default and changes
+// into dir, where a .env file may be written
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	path := filepath.Join(dir, "config", "moco", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, dir)
+}
+
+const layeredConfig = `
+domain = "shared"
+api_key = "file-key"
+weekly_target = 38
+profile = "work"
+
+[profiles.work]
+domain = "acme"
+
+[profiles.side]
+base_url = "http://localhost:8080/api/v1"
+api_key = "side-key"
+
+[keys]
+delete = "D"
+`
+
+func TestLoadConfigLayers(t *testing.T) {
+	dir := isolate(t)
+	writeConfig(t, dir, layeredConfig)
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("MOCO_API_KEY=dotenv-key\nMOCO_GITHUB_REPO=acme/app\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(ConfigFlags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The profile overrides the top level, which overrides .env
+	if cfg.Profile != "work" || cfg.MocoDomain != "acme" || cfg.MocoAPIKey != "file-key" || cfg.WeeklyTarget != 38 {
+		t.Errorf("got profile %q, domain %q, key %q, target %v", cfg.Profile, cfg.MocoDomain, cfg.MocoAPIKey, cfg.WeeklyTarget)
+	}
+	if len(cfg.TicketRules) != 1 || cfg.Sources["MOCO_GITHUB_REPO"] != ".env" {
+		t.Errorf("the GitHub repo from .env was lost: %v", cfg.Sources)
+	}
+	if cfg.Sources["MOCO_DOMAIN"] != "profile work in "+cfg.File {
+		t.Errorf("domain from %q", cfg.Sources["MOCO_DOMAIN"])
+	}
+	if cfg.Keys["delete"][0] != "D" || strings.Join(cfg.Profiles, ",") != "side,work" {
+		t.Errorf("keys %v, profiles %v", cfg.Keys, cfg.Profiles)
+	}
+
+	// The environment overrides the file, and flags override the environment
+	t.Setenv("MOCO_API_KEY", "env-key")
+	t.Setenv("MOCO_DOMAIN", "env-domain")
+	cfg, err = loadConfig(ConfigFlags{Domain: "flag-domain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MocoAPIKey != "env-key" || cfg.MocoDomain != "flag-domain" || cfg.Sources["MOCO_DOMAIN"] != "--domain flag" {
+		t.Errorf("got key %q, domain %q from %q", cfg.MocoAPIKey, cfg.MocoDomain, cfg.Sources["MOCO_DOMAIN"])
+	}
+}
+
+func TestLoadConfigProfileSelection(t *testing.T) {
+	dir := isolate(t)
+	writeConfig(t, dir, layeredConfig)
+
+	t.Setenv("MOCO_PROFILE", "side")
+	cfg, err := loadConfig(ConfigFlags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The base URL names the account when the profile sets no domain of
+	// its own, but the shared one still does
+	if cfg.Profile != "side" || cfg.MocoBaseURL != "http://localhost:8080/api/v1/" || cfg.MocoDomain != "shared" || cfg.MocoAPIKey != "side-key" {
+		t.Errorf("got %+v", cfg)
+	}
+
+	// The flag beats MOCO_PROFILE
+	if cfg, err := loadConfig(ConfigFlags{Profile: "work"}); err != nil || cfg.Profile != "work" {
+		t.Errorf("--profile work: %v, %v", cfg, err)
+	}
+
+	_, err = loadConfig(ConfigFlags{Profile: "missing"})
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || !strings.Contains(err.Error(), "side, work") {
+		t.Errorf("unknown profile: %v", err)
+	}
+}
+
+func TestLoadConfigInvalidSetting(t *testing.T) {
+	dir := isolate(t)
+	writeConfig(t, dir, "domain = \"acme\"\napi_key = \"key\"\n\n[profiles.work]\ncredential_ttl = \"soon\"\n")
+
+	_, err := loadConfig(ConfigFlags{Profile: "work"})
+	if err == nil || !strings.Contains(err.Error(), "MOCO_CREDENTIAL_TTL (from profile work in ") {
+		t.Errorf("err = %v, want it to name the setting and the profile", err)
+	}
+}
+
+func TestLoadConfigInvalidWeeklyTarget(t *testing.T) {
+	dir := isolate(t)
+	writeConfig(t, dir, "domain = \"acme\"\napi_key = \"key\"\n")
+
+	for _, target := range []string{"NaN", "Inf", "1e300"} {
+		t.Setenv("MOCO_WEEKLY_TARGET", target)
+		_, err := loadConfig(ConfigFlags{})
+		if err == nil || !strings.Contains(err.Error(), "MOCO_WEEKLY_TARGET") {
+			t.Errorf("weekly target %s: err = %v, want it rejected", target, err)
+		}
+	}
+}
+
+func TestLoadConfigMissingSettings(t *testing.T) {
+	dir := isolate(t)
+	writeConfig(t, dir, "[profiles.work]\ndomain = \"acme\"\napi_key = \"key\"\n")
+
+	// Profiles exist, but none is chosen
+	_, err := loadConfig(ConfigFlags{})
+	if err == nil || !strings.Contains(err.Error(), "no profile selected") {
+		t.Errorf("err = %v, want a hint to select a profile", err)
+	}
+}
+
+func TestReadConfigFileErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown setting":   "colour = \"red\"\n",
+		"bad profile name":  "[profiles.\"a b\"]\ndomain = \"acme\"\n",
+		"keys in a profile": "[profiles.work.keys]\nquit = \"q\"\n",
+		"undefined default": "profile = \"work\"\n",
+		"not a string":      "domain = [\"acme\"]\n",
+	} {
+		dir := isolate(t)
+		writeConfig(t, dir, content)
+		if _, err := readConfigFile(""); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denwerk/moco/src/api"
+	"github.com/denwerk/moco/src/ticket"
+	"github.com/denwerk/moco/src/types"
+)
+
+// copyItem is an entry to copy, with the hours and description the copies
+// get after review
+type copyItem struct {
+	Source      types.TimeEntry
+	Hours       string
+	Description string
+}
+
+// newCopyItems prepares entries for copying, unchanged
+func newCopyItems(entries []types.TimeEntry) []copyItem {
+	items := make([]copyItem, len(entries))
+	for i, e := range entries {
+		items[i] = copyItem{
+			Source:      e,
+			Hours:       strconv.FormatFloat(e.Hours, 'f', -1, 64),
+			Description: e.Description,
+		}
+	}
+	return items
+}
+
+// leftOut reports whether the item is not to be copied: its hours are "-"
+// or zero in any notation, e.g. "0:00", "0.0" or "0,0"
+func (item copyItem) leftOut() bool {
+	hours := strings.TrimSpace(item.Hours)
+	if hours == "-" {
+		return true
+	}
+	hours = strings.Replace(hours, ",", ".", 1)
+	if h, err := strconv.ParseFloat(hours, 64); err == nil {
+		return h == 0
+	}
+	hh, mm, ok := strings.Cut(hours, ":")
+	if !ok {
+		return false
+	}
+	h, err1 := strconv.ParseFloat(hh, 64)
+	m, err2 := strconv.ParseFloat(mm, 64)
+	return err1 == nil && err2 == nil && h == 0 && m == 0
+}
+
+// copyDates lists the days from through until, both YYYY-MM-DD. An empty
+// until means just from. Ranges leave out weekends unless weekends is set.
+func copyDates(from, until string, weekends bool) ([]string, error) {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, usageErrorf("invalid target date %q", from)
+	}
+	if until == "" || until == from {
+		return []string{from}, nil
+	}
+	end, err := time.Parse("2006-01-02", until)
+	if err != nil {
+		return nil, usageErrorf("invalid target date %q", until)
+	}
+	if end.Before(start) {
+		return nil, usageErrorf("target range starts after it ends: %s > %s", from, until)
+	}
+
+	var dates []string
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if !weekends && (day.Weekday() == time.Saturday || day.Weekday() == time.Sunday) {
+			continue
+		}
+		dates = append(dates, day.Format("2006-01-02"))
+	}
+	if len(dates) == 0 {
+		return nil, usageErrorf("the target range %s to %s has only weekend days", from, until)
+	}
+	return dates, nil
+}
+
+// buildCopies validates the reviewed items like the form does and returns
+// one entry per item and date. Items left out during review are skipped.
+func buildCopies(items []copyItem, dates []string, rules []ticket.Rule) ([]types.TimeEntry, error) {
+	var copies []types.TimeEntry
+	for _, date := range dates {
+		for _, item := range items {
+			if item.leftOut() {
+				continue
+			}
+			src := item.Source
+			entry, err := newTimeEntry(entryInput{
+				ProjectID:   firstNonZero(src.ProjectID, src.Project.ID),
+				TaskID:      firstNonZero(src.TaskID, src.Task.ID),
+				Date:        date,
+				Hours:       strings.Replace(strings.TrimSpace(item.Hours), ",", ".", 1),
+				Description: item.Description,
+				Tag:         src.Tag,
+				Billable:    src.Billable,
+			}, rules)
+			if err != nil {
+				return nil, usageErrorf("%s / %s: %v", src.Project.Name, src.Task.Name, err)
+			}
+			// Keep the ticket unless the new description names another one
+			if entry.RemoteID == "" {
+				entry.RemoteService, entry.RemoteID, entry.RemoteURL = src.RemoteService, src.RemoteID, src.RemoteURL
+			}
+			entry.Project, entry.Task, entry.Customer = src.Project, src.Task, src.Customer
+			copies = append(copies, entry)
+		}
+	}
+	return copies, nil
+}
+
+// skipBooked drops the copies that already exist, so copying twice doesn't
+// book twice. It returns the copies left and how many were dropped.
+func skipBooked(ctx context.Context, client *api.Client, copies []types.TimeEntry) ([]types.TimeEntry, int, error) {
+	if len(copies) == 0 {
+		return nil, 0, nil
+	}
+	from, to := copies[0].Date, copies[0].Date
+	for _, c := range copies {
+		if c.Date < from {
+			from = c.Date
+		}
+		if c.Date > to {
+			to = c.Date
+		}
+	}
+
+	existing, _, err := client.Activities(ctx, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+	used := map[int]bool{}
+	var left []types.TimeEntry
+	for _, c := range copies {
+		booked := false
+		for _, e := range existing {
+			if !used[e.ID] && api.SameActivity(e, c) {
+				used[e.ID] = true
+				booked = true
+				break
+			}
+		}
+		if !booked {
+			left = append(left, c)
+		}
+	}
+	return left, len(copies) - len(left), nil
+}
+
+func firstNonZero(values ...int) int {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/denwerk/moco/src/mocotest"
+	"github.com/denwerk/moco/src/types"
+)
+
+func copySource() types.TimeEntry {
+	billable := true
+	return types.TimeEntry{
+		ID:            1,
+		Date:          "2026-10-12",
+		Hours:         1.5,
+		Description:   "Review of ACME-42",
+		Tag:           "review",
+		Billable:      &billable,
+		RemoteService: "jira",
+		RemoteID:      "ACME-42",
+		RemoteURL:     "https://jira.example.com/browse/ACME-42",
+		Project:       types.Project{ID: 1000, Name: "ACME Website"},
+		Task:          types.Task{ID: 2000, Name: "Development"},
+		Customer:      types.Customer{ID: 100, Name: "ACME Corp"},
+	}
+}
+
+func TestCopyItemLeftOut(t *testing.T) {
+	tests := map[string]bool{
+		"-":     true,
+		" - ":   true,
+		"0":     true,
+		"0.0":   true,
+		"0,0":   true,
+		"0:00":  true,
+		"00:00": true,
+		"-0":    true,
+		"0,5":   false,
+		"0:30":  false,
+		"1.5":   false,
+		"":      false,
+		"abc":   false,
+	}
+	for hours, want := range tests {
+		if got := (copyItem{Hours: hours}).leftOut(); got != want {
+			t.Errorf("leftOut(%q) = %v, want %v", hours, got, want)
+		}
+	}
+}
+
+func TestBuildCopies(t *testing.T) {
+	other := copySource()
+	other.Project = types.Project{ID: 1002, Name: "Internal"}
+	other.Task = types.Task{ID: 2020, Name: "Standup"}
+	other.Description = "Standup"
+
+	items := newCopyItems([]types.TimeEntry{copySource(), other, copySource()})
+	items[0].Hours = "0,5"
+	items[1].Hours = "0:00"
+	items[2].Description = "Review of ACME-43"
+
+	copies, err := buildCopies(items, []string{"2026-10-13", "2026-10-14"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copies) != 4 {
+		t.Fatalf("got %d copies, want 2 items on 2 days", len(copies))
+	}
+	for i, c := range copies {
+		wantDate := []string{"2026-10-13", "2026-10-13", "2026-10-14", "2026-10-14"}[i]
+		if c.Date != wantDate || c.ProjectID != 1000 || c.TaskID != 2000 || c.Project.Name != "ACME Website" {
+			t.Errorf("copy %d = %s, project %d, task %d, want %s on 1000/2000", i, c.Date, c.ProjectID, c.TaskID, wantDate)
+		}
+		if c.Tag != "review" || c.RemoteID != "ACME-42" || c.ID != 0 {
+			t.Errorf("copy %d has tag %q, ticket %q, ID %d, want the source's tag and ticket and no ID", i, c.Tag, c.RemoteID, c.ID)
+		}
+	}
+	if copies[0].Hours != 0.5 || copies[1].Hours != 1.5 {
+		t.Errorf("hours = %v, %v, want 0.5 and 1.5", copies[0].Hours, copies[1].Hours)
+	}
+}
+
+func TestBuildCopiesNamesInvalidItem(t *testing.T) {
+	items := newCopyItems([]types.TimeEntry{copySource()})
+	items[0].Hours = "1:75"
+
+	_, err := buildCopies(items, []string{"2026-10-13"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "ACME Website / Development") {
+		t.Errorf("err = %v, want it to name the item", err)
+	}
+}
+
+func TestSkipBooked(t *testing.T) {
+	isolate(t)
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	client := newReplayClient(t, srv)
+	ctx := context.Background()
+
+	items := newCopyItems([]types.TimeEntry{copySource(), copySource()})
+	items[1].Description = "Review of ACME-43"
+	copies, err := buildCopies(items, []string{"2026-10-13", "2026-10-14"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	left, skipped, err := skipBooked(ctx, client, copies)
+	if err != nil || len(left) != 4 || skipped != 0 {
+		t.Fatalf("first copy leaves %d, skips %d, err %v, want all 4 to book", len(left), skipped, err)
+	}
+	if _, err := client.CreateActivity(ctx, left[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Copying again after part of the copies went through books only the rest
+	left, skipped, err = skipBooked(ctx, client, copies)
+	if err != nil || len(left) != 3 || skipped != 1 {
+		t.Fatalf("second copy leaves %d, skips %d, err %v, want 3 and 1", len(left), skipped, err)
+	}
+	for _, c := range left {
+		if _, err := client.CreateActivity(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Copying twice doesn't book twice
+	left, skipped, err = skipBooked(ctx, client, copies)
+	if err != nil || len(left) != 0 || skipped != 4 {
+		t.Errorf("third copy leaves %d, skips %d, err %v, want nothing left", len(left), skipped, err)
+	}
+	if n := len(srv.Activities()); n != 4 {
+		t.Errorf("server has %d entries, want 4", n)
+	}
+}
+
+func TestSkipBookedKeepsIntendedDuplicates(t *testing.T) {
+	isolate(t)
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	client := newReplayClient(t, srv)
+	ctx := context.Background()
+
+	// The same entry twice on the target day, one of them booked already
+	copies, err := buildCopies(newCopyItems([]types.TimeEntry{copySource(), copySource()}), []string{"2026-10-13"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateActivity(ctx, copies[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	left, skipped, err := skipBooked(ctx, client, copies)
+	if err != nil || len(left) != 1 || skipped != 1 {
+		t.Errorf("leaves %d, skips %d, err %v, want one booked entry to cover one copy", len(left), skipped, err)
+	}
+}
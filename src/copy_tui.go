@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/denwerk/moco/src/api"
+	"github.com/denwerk/moco/src/types"
+	"github.com/denwerk/moco/src/ui"
+)
+
+// entriesCopiedMsg is sent when copies of time entries were submitted
+type entriesCopiedMsg struct {
+	client  *api.Client // Client that sent them, to drop results for a previous profile
+	booked  int
+	skipped int
+	failed  []types.TimeEntry
+	sent    []time.Time // When each failed copy was sent
+	errs    []error
+	err     error // Set if nothing could be submitted
+}
+
+// toggleCopyMark marks or unmarks the selected entry for copying
+func (m *Model) toggleCopyMark() {
+	if m.selectedEntry == nil {
+		return
+	}
+	if m.copyMarks == nil {
+		m.copyMarks = map[int]bool{}
+	}
+	id := m.selectedEntry.ID
+	if m.copyMarks[id] {
+		delete(m.copyMarks, id)
+	} else {
+		m.copyMarks[id] = true
+	}
+	m.updateTable()
+}
+
+// openCopyForm starts copying the marked entries, or if none are marked,
+// all entries of the selected entry's day
+func (m *Model) openCopyForm() {
+	var sources []types.TimeEntry
+	for _, e := range m.timeEntries {
+		if m.copyMarks[e.ID] {
+			sources = append(sources, e)
+		}
+	}
+	if len(sources) == 0 && m.selectedEntry != nil {
+		for _, e := range m.timeEntries {
+			if e.Date == m.selectedEntry.Date {
+				sources = append(sources, e)
+			}
+		}
+	}
+	if len(sources) == 0 {
+		m.setMessage(fmt.Sprintf("Select an entry or mark entries with '%s' to copy", m.keys.Keys("mark")), true)
+		return
+	}
+
+	var items []ui.CopyItem
+	for _, item := range newCopyItems(sources) {
+		items = append(items, ui.CopyItem{
+			Label:       item.Source.Project.Name + " / " + item.Source.Task.Name,
+			Hours:       item.Hours,
+			Description: item.Description,
+		})
+	}
+	m.confirmDelete = false
+	m.copySources = sources
+	m.copyForm = ui.NewCopyForm(items, time.Now().Format("2006-01-02"))
+	m.copyForm.SetKeyHints(m.keys.Keys("submit"), m.keys.Keys("back"), m.keys.Keys("next_pane"))
+}
+
+// handleCopyKey handles keys while the copy dialog is open. Like in the
+// form, typed characters always go to the focused field.
+func (m *Model) handleCopyKey(msg tea.KeyMsg) tea.Cmd {
+	if isTextKey(msg) {
+		m.copyForm.Update(msg)
+		return nil
+	}
+
+	switch m.keys.Action(msg.String(), scopeGlobal) {
+	case "quit":
+		return m.quit()
+	case "back":
+		m.copyForm = nil
+		m.copySources = nil
+		return nil
+	case "submit":
+		return m.submitCopies()
+	case "up", "prev_pane":
+		m.copyForm.PrevField()
+		return nil
+	case "down", "next_pane":
+		m.copyForm.NextField()
+		return nil
+	}
+	m.copyForm.Update(msg)
+	return nil
+}
+
+// submitCopies validates the dialog and books the copies in the background.
+// Copies that already exist on the target days are left out.
+func (m *Model) submitCopies() tea.Cmd {
+	target, until, hours, descs := m.copyForm.Values()
+	now := time.Now()
+	from, err := parseDate(target, now)
+	if err == nil && until != "" {
+		until, err = parseDate(until, now)
+	}
+	var dates []string
+	if err == nil {
+		dates, err = copyDates(from, until, false)
+	}
+	if err != nil {
+		m.copyForm.SetError(err.Error())
+		return nil
+	}
+
+	items := newCopyItems(m.copySources)
+	for i := range items {
+		items[i].Hours, items[i].Description = hours[i], descs[i]
+	}
+	copies, err := buildCopies(items, dates, m.cfg.TicketRules)
+	if err != nil {
+		m.copyForm.SetError(err.Error())
+		return nil
+	}
+
+	m.copyForm = nil
+	m.copySources = nil
+	m.copyMarks = nil
+	m.updateTable()
+
+	client := m.client
+	return func() tea.Msg {
+		ctx, cancel := requestContext()
+		left, skipped, err := skipBooked(ctx, client, copies)
+		cancel()
+		if err != nil {
+			return entriesCopiedMsg{client: client, failed: copies, errs: repeatErr(err, len(copies)), err: err}
+		}
+
+		msg := entriesCopiedMsg{client: client, skipped: skipped}
+		for _, c := range left {
+			ctx, cancel := requestContext()
+			sentAt := time.Now()
+			_, err := client.CreateActivity(ctx, c)
+			cancel()
+			if err != nil {
+				msg.failed = append(msg.failed, c)
+				msg.sent = append(msg.sent, sentAt)
+				msg.errs = append(msg.errs, err)
+				continue
+			}
+			msg.booked++
+		}
+		return msg
+	}
+}
+
+// handleEntriesCopied queues the copies that failed for lack of
+// connectivity and reports the rest
+func (m *Model) handleEntriesCopied(msg entriesCopiedMsg) tea.Cmd {
+	if msg.client != m.client {
+		if len(msg.errs) > 0 {
+			m.dropStaleWrite(msg.errs[0])
+		}
+		return nil
+	}
+	m.retryStatus = ""
+	var lastErr error
+	for i, entry := range msg.failed {
+		if shouldQueue(msg.errs[i]) {
+			op := QueuedOp{Kind: OpCreate, Entry: entry}
+			if msg.err == nil {
+				op = queuedCreate(entry, msg.sent[i], msg.errs[i])
+			}
+			m.queueWrite(op, msg.errs[i])
+			continue
+		}
+		lastErr = msg.errs[i]
+	}
+
+	switch {
+	case lastErr != nil:
+		m.setMessage(fmt.Sprintf("Copied %d entries, error copying others: %s", msg.booked, describeError(lastErr)), true)
+	case len(msg.failed) == 0:
+		text := fmt.Sprintf("Copied %d entries", msg.booked)
+		if msg.skipped > 0 {
+			text += fmt.Sprintf(", %d already booked", msg.skipped)
+		}
+		m.setMessage(text, false)
+	}
+	return m.loadTimeEntries()
+}
+
+func repeatErr(err error, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// The agent is a background 'moco auth agent' process that keeps an
+// unlocked API key in memory until its TTL runs out, so the passphrase is
+// asked once per session. It listens on a socket in the state directory,
+// which only the user can enter.
+const agentSocket = "agent.sock"
+
+// agentRequest asks the agent for its key ("get") or to quit ("stop")
+type agentRequest struct {
+	Op string `json:"op"`
+}
+
+// agentStatus is the agent's answer
+type agentStatus struct {
+	APIKey  string    `json:"api_key,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+// askAgent sends a request to the agent of dir
+func askAgent(dir, op string) (*agentStatus, error) {
+	conn, err := net.DialTimeout("unix", filepath.Join(dir, agentSocket), time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(agentRequest{Op: op}); err != nil {
+		return nil, err
+	}
+	var status agentStatus
+	if err := json.NewDecoder(conn).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// agentGet returns the key held by the agent of dir, if one is running
+func agentGet(dir string) (*agentStatus, bool) {
+	status, err := askAgent(dir, "get")
+	if err != nil || status.APIKey == "" {
+		return nil, false
+	}
+	return status, true
+}
+
+// stopAgent makes the agent of dir forget its key and quit. It reports
+// whether an agent was running.
+func stopAgent(dir string) bool {
+	_, err := askAgent(dir, "stop")
+	return err == nil
+}
+
+// startAgent starts an agent for dir that holds apiKey for ttl, replacing
+// any agent already running there. The key is passed on stdin so it never
+// shows up in the process list.
+func startAgent(dir, apiKey string, ttl time.Duration) error {
+	stopAgent(dir)
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(executable, "auth", "agent", "--dir", dir, "--ttl", ttl.String())
+	cmd.Stdin = strings.NewReader(apiKey + "\n")
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting agent: %v", err)
+	}
+	defer cmd.Process.Release()
+
+	// Wait until it answers, so the next command finds it
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); {
+		if _, ok := agentGet(dir); ok {
+			return nil
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return errors.New("the agent didn't start")
+}
+
+// runAgent serves the key read from stdin on the socket of dir until ttl
+// has passed or it is told to stop
+func runAgent(args []string) int {
+	fs, _ := newFlagSet("auth agent", "--dir <state directory> --ttl <duration>")
+	dir := fs.String("dir", "", "state directory to listen in")
+	ttl := fs.Duration("ttl", DefaultCredentialTTL, "how long to keep the key")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *dir == "" {
+		return fail(usageErrorf("--dir is required"))
+	}
+
+	apiKey, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return fail(usageErrorf("expected the API key on stdin"))
+	}
+
+	// Outlive the terminal and the command that started us
+	signal.Ignore(os.Interrupt, syscall.SIGHUP)
+
+	if err := serveAgent(*dir, apiKey, *ttl); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+// serveAgent holds apiKey on the socket of dir until ttl has passed or the
+// agent is told to stop
+func serveAgent(dir, apiKey string, ttl time.Duration) error {
+	path := filepath.Join(dir, agentSocket)
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %v", path, err)
+	}
+	defer listener.Close()
+	if err := os.Chmod(path, 0600); err != nil {
+		return err
+	}
+
+	expires := time.Now().Add(ttl)
+	timer := time.AfterFunc(ttl, func() { listener.Close() })
+	defer timer.Stop()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return nil
+		}
+		if stop := serveAgentConn(conn, apiKey, expires); stop {
+			return nil
+		}
+	}
+}
+
+// serveAgentConn answers one request and reports whether to quit
+func serveAgentConn(conn net.Conn, apiKey string, expires time.Time) bool {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	var request agentRequest
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		return false
+	}
+	switch request.Op {
+	case "get":
+		if !time.Now().Before(expires) {
+			// Accepted just before the listener closed
+			json.NewEncoder(conn).Encode(agentStatus{Expires: expires})
+			return true
+		}
+		json.NewEncoder(conn).Encode(agentStatus{APIKey: apiKey, Expires: expires})
+	case "stop":
+		json.NewEncoder(conn).Encode(agentStatus{Expires: time.Now()})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentLocksKeyWhenTTLExpires(t *testing.T) {
+	dir := t.TempDir()
+	sealed, err := sealAPIKey("secret-key", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveCredentials(dir, sealed); err != nil {
+		t.Fatal(err)
+	}
+	prompt := passphrasePrompt
+	passphrasePrompt = nil
+	defer func() { passphrasePrompt = prompt }()
+
+	const ttl = 500 * time.Millisecond
+	done := make(chan error)
+	go func() { done <- serveAgent(dir, "secret-key", ttl) }()
+
+	// While the agent holds the key, no passphrase is needed
+	deadline := time.Now().Add(ttl)
+	for {
+		if _, ok := agentGet(dir); ok || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if apiKey, err := unlockAPIKey(dir, time.Hour); err != nil || apiKey != "secret-key" {
+		t.Fatalf("while unlocked: %q, %v", apiKey, err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent still running after its TTL")
+	}
+	if status, ok := agentGet(dir); ok {
+		t.Errorf("expired agent handed out %+v", status)
+	}
+	_, err = unlockAPIKey(dir, time.Hour)
+	if err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("after the TTL: err = %v, want locked", err)
+	}
+}
+
+func TestAgentStop(t *testing.T) {
+	dir := t.TempDir()
+	done := make(chan error)
+	go func() { done <- serveAgent(dir, "secret-key", time.Hour) }()
+
+	for i := 0; i < 100; i++ {
+		if _, ok := agentGet(dir); ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !stopAgent(dir) {
+		t.Fatal("no agent to stop")
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent still running after stop")
+	}
+	if _, ok := agentGet(dir); ok {
+		t.Error("stopped agent still answers")
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"golang.org/x/crypto/argon2"
+)
+
+// credentialsFile holds the encrypted API key in the state directory, so
+// each profile has its own
+const credentialsFile = "credentials.json"
+
+// DefaultCredentialTTL is how long the agent keeps an unlocked API key
+// when MOCO_CREDENTIAL_TTL is not set
+const DefaultCredentialTTL = 8 * time.Hour
+
+// credentialsAD binds the ciphertext to its purpose and format version
+var credentialsAD = []byte("moco-credentials-v1")
+
+var errWrongPassphrase = errors.New("wrong passphrase")
+
+// CredentialStore is the API key sealed with AES-256-GCM under a key
+// derived from a passphrase with Argon2id. Byte slices are base64 in JSON.
+type CredentialStore struct {
+	Version    int       `json:"version"`
+	Salt       []byte    `json:"salt"`
+	Time       uint32    `json:"time"`
+	Memory     uint32    `json:"memory"` // KiB
+	Threads    uint8     `json:"threads"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// sealAPIKey encrypts apiKey with a key derived from passphrase
+func sealAPIKey(apiKey, passphrase string) (*CredentialStore, error) {
+	store := &CredentialStore{
+		Version:   1,
+		Salt:      make([]byte, 16),
+		Time:      3,
+		Memory:    64 * 1024,
+		Threads:   4,
+		CreatedAt: time.Now(),
+	}
+	if _, err := rand.Read(store.Salt); err != nil {
+		return nil, fmt.Errorf("error generating salt: %v", err)
+	}
+
+	aead, err := store.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	store.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(store.Nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %v", err)
+	}
+	store.Ciphertext = aead.Seal(nil, store.Nonce, []byte(apiKey), credentialsAD)
+	return store, nil
+}
+
+// Open decrypts the API key, or returns errWrongPassphrase
+func (s *CredentialStore) Open(passphrase string) (string, error) {
+	if s.Version != 1 {
+		return "", fmt.Errorf("unsupported credential store version %d", s.Version)
+	}
+	aead, err := s.cipher(passphrase)
+	if err != nil {
+		return "", err
+	}
+	plain, err := aead.Open(nil, s.Nonce, s.Ciphertext, credentialsAD)
+	if err != nil {
+		return "", errWrongPassphrase
+	}
+	return string(plain), nil
+}
+
+func (s *CredentialStore) cipher(passphrase string) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), s.Salt, s.Time, s.Memory, s.Threads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// LoadCredentials reads the credential store of a state directory. It
+// returns nil without error if there is none.
+func LoadCredentials(dir string) (*CredentialStore, error) {
+	data, err := os.ReadFile(filepath.Join(dir, credentialsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading credential store: %v", err)
+	}
+
+	var store CredentialStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("error unmarshaling credential store: %v", err)
+	}
+	return &store, nil
+}
+
+// SaveCredentials writes the credential store atomically, readable only by
+// the user
+func SaveCredentials(dir string, store *CredentialStore) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling credential store: %v", err)
+	}
+
+	path := filepath.Join(dir, credentialsFile)
+	tmpFile := path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		return fmt.Errorf("error writing credential store: %v", err)
+	}
+	if err := os.Rename(tmpFile, path); err != nil {
+		return fmt.Errorf("error replacing credential store: %v", err)
+	}
+	return nil
+}
+
+// RemoveCredentials deletes the credential store; a missing one is fine
+func RemoveCredentials(dir string) error {
+	err := os.Remove(filepath.Join(dir, credentialsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing credential store: %v", err)
+	}
+	return nil
+}
+
+// passphrasePrompt asks for the passphrase of the credential store. main
+// clears it before starting the TUI, which owns the terminal from then on.
+var passphrasePrompt = readPassphrase
+
+// readPassphrase reads a line from the terminal without echoing it
+func readPassphrase(question string) (string, error) {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return "", errors.New("not a terminal")
+	}
+	fmt.Fprint(os.Stderr, question)
+	passphrase, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	return string(passphrase), err
+}
+
+// unlockAPIKey returns the API key of the credential store in dir, or ""
+// without a store. It asks the agent first; otherwise it asks for the
+// passphrase and hands the key to a new agent for ttl.
+func unlockAPIKey(dir string, ttl time.Duration) (string, error) {
+	store, err := LoadCredentials(dir)
+	if err != nil || store == nil {
+		return "", err
+	}
+	if status, ok := agentGet(dir); ok {
+		return status.APIKey, nil
+	}
+
+	locked := &ConfigError{"the stored API key is locked, run 'moco auth unlock' in a terminal"}
+	if passphrasePrompt == nil {
+		return "", locked
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		passphrase, err := passphrasePrompt("Passphrase for the Moco API key: ")
+		if err != nil {
+			return "", locked
+		}
+		apiKey, err := store.Open(passphrase)
+		if errors.Is(err, errWrongPassphrase) {
+			fmt.Fprintln(os.Stderr, "Wrong passphrase, try again.")
+			continue
+		}
+		if err != nil {
+			return "", &ConfigError{err.Error()}
+		}
+		if err := startAgent(dir, apiKey, ttl); err != nil {
+			fmt.Fprintf(os.Stderr, "moco: the key stays locked for the next command: %v\n", err)
+		}
+		return apiKey, nil
+	}
+	return "", &ConfigError{errWrongPassphrase.Error()}
+}
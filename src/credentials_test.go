@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSealAPIKeyRoundTrip(t *testing.T) {
+	store, err := sealAPIKey("secret-key", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKey, err := store.Open("correct horse")
+	if err != nil || apiKey != "secret-key" {
+		t.Errorf("Open = %q, %v", apiKey, err)
+	}
+	if _, err := store.Open("wrong horse"); !errors.Is(err, errWrongPassphrase) {
+		t.Errorf("wrong passphrase: err = %v", err)
+	}
+}
+
+func TestOpenTamperedStore(t *testing.T) {
+	sealed, err := sealAPIKey("secret-key", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tamper := map[string]func(s *CredentialStore){
+		"ciphertext": func(s *CredentialStore) { s.Ciphertext[0] ^= 1 },
+		"tag":        func(s *CredentialStore) { s.Ciphertext[len(s.Ciphertext)-1] ^= 1 },
+		"nonce":      func(s *CredentialStore) { s.Nonce[0] ^= 1 },
+		"salt":       func(s *CredentialStore) { s.Salt[0] ^= 1 },
+	}
+	for name, change := range tamper {
+		store := *sealed
+		store.Ciphertext = append([]byte(nil), sealed.Ciphertext...)
+		store.Nonce = append([]byte(nil), sealed.Nonce...)
+		store.Salt = append([]byte(nil), sealed.Salt...)
+		change(&store)
+		if apiKey, err := store.Open("pass"); err == nil {
+			t.Errorf("%s changed: opened as %q", name, apiKey)
+		}
+	}
+
+	store := *sealed
+	store.Version = 2
+	if _, err := store.Open("pass"); err == nil {
+		t.Error("unknown version opened")
+	}
+}
+
+func TestSaveCredentials(t *testing.T) {
+	dir := t.TempDir()
+	sealed, err := sealAPIKey("secret-key", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveCredentials(dir, sealed); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, credentialsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("mode = %o, want 600", perm)
+	}
+
+	loaded, err := LoadCredentials(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiKey, err := loaded.Open("pass"); err != nil || apiKey != "secret-key" {
+		t.Errorf("loaded store opens to %q, %v", apiKey, err)
+	}
+
+	if err := RemoveCredentials(dir); err != nil {
+		t.Fatal(err)
+	}
+	if store, err := LoadCredentials(dir); store != nil || err != nil {
+		t.Errorf("after removing: %v, %v", store, err)
+	}
+}
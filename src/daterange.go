@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// dateRange is an inclusive range of days in YYYY-MM-DD form
+type dateRange struct {
+	From string
+	To   string
+}
+
+// rangePresets lists the names accepted by presetRange
+var rangePresets = []string{"today", "yesterday", "this-week", "last-week", "last-7-days", "this-month", "last-month"}
+
+// presetRange returns the range a preset name refers to. Weeks start on Monday.
+func presetRange(preset string, now time.Time) (dateRange, bool) {
+	day := func(t time.Time) string { return t.Format("2006-01-02") }
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	firstOfMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+
+	switch strings.ToLower(preset) {
+	case "today":
+		return dateRange{day(today), day(today)}, true
+	case "yesterday":
+		yesterday := today.AddDate(0, 0, -1)
+		return dateRange{day(yesterday), day(yesterday)}, true
+	case "this-week", "week":
+		return dateRange{day(monday), day(monday.AddDate(0, 0, 6))}, true
+	case "last-week":
+		return dateRange{day(monday.AddDate(0, 0, -7)), day(monday.AddDate(0, 0, -1))}, true
+	case "last-7-days":
+		return dateRange{day(today.AddDate(0, 0, -6)), day(today)}, true
+	case "this-month", "month":
+		return dateRange{day(firstOfMonth), day(firstOfMonth.AddDate(0, 1, -1))}, true
+	case "last-month":
+		return dateRange{day(firstOfMonth.AddDate(0, -1, 0)), day(firstOfMonth.AddDate(0, 0, -1))}, true
+	}
+	return dateRange{}, false
+}
+
+// resolveRange combines a preset with explicit --from/--to values, which
+// win over the preset. Both bounds accept everything parseDate does.
+func resolveRange(preset, from, to string, now time.Time) (dateRange, error) {
+	var r dateRange
+	if preset != "" {
+		var ok bool
+		r, ok = presetRange(preset, now)
+		if !ok {
+			return dateRange{}, usageErrorf("unknown range %q, use one of %s", preset, strings.Join(rangePresets, ", "))
+		}
+	}
+
+	if from != "" {
+		date, err := parseDate(from, now)
+		if err != nil {
+			return dateRange{}, err
+		}
+		r.From = date
+	}
+	if to != "" {
+		date, err := parseDate(to, now)
+		if err != nil {
+			return dateRange{}, err
+		}
+		r.To = date
+	}
+
+	switch {
+	case r.From == "" && r.To == "":
+		r, _ = presetRange("today", now)
+	case r.From == "":
+		r.From = r.To
+	case r.To == "":
+		r.To = now.Format("2006-01-02")
+	}
+
+	if r.From > r.To {
+		return dateRange{}, usageErrorf("range starts after it ends: %s > %s", r.From, r.To)
+	}
+	return r, nil
+}
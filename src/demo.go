@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/denwerk/moco/src/mocotest"
+	"github.com/denwerk/moco/src/ticket"
+)
+
+// startDemo serves a fake Moco account and returns a config pointing at it.
+// Local state goes to a temporary directory so the real ~/.moco is never
+// touched. The returned function shuts everything down.
+func startDemo() (*Config, func(), error) {
+	dir, err := os.MkdirTemp("", "moco-demo-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating demo directory: %v", err)
+	}
+	if err := os.Setenv("MOCO_CONFIG_DIR", dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+
+	server := mocotest.NewServer()
+	cfg := &Config{
+		MocoDomain:   "demo",
+		MocoAPIKey:   server.APIKey(),
+		MocoBaseURL:  server.URL(),
+		TicketRules:  []ticket.Rule{ticket.JiraRule("https://demo.atlassian.net/browse/")},
+		WeeklyTarget: DefaultWeeklyTarget,
+		Theme:        os.Getenv("MOCO_THEME"),
+	}
+
+	stop := func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+	return cfg, stop, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denwerk/moco/src/ticket"
+	"github.com/denwerk/moco/src/types"
+)
+
+// entryInput is a time entry as typed into the form or passed on the command line
+type entryInput struct {
+	ProjectID   int
+	TaskID      int
+	Date        string
+	Hours       string
+	Description string
+	Ticket      string
+	Tag         string
+	Billable    *bool // nil leaves the choice to the server
+}
+
+// newTimeEntry validates input and turns it into an entry ready to submit
+func newTimeEntry(input entryInput, rules []ticket.Rule) (types.TimeEntry, error) {
+	// Validate hours
+	hours, err := parseHours(input.Hours)
+	if err != nil {
+		return types.TimeEntry{}, err
+	}
+
+	// Validate date
+	if input.Date == "" {
+		return types.TimeEntry{}, fmt.Errorf("Date is required")
+	}
+	if _, err := time.Parse("2006-01-02", input.Date); err != nil {
+		return types.TimeEntry{}, fmt.Errorf("Date must be in YYYY-MM-DD format")
+	}
+
+	// Validate description
+	if input.Description == "" {
+		return types.TimeEntry{}, fmt.Errorf("Description is required")
+	}
+
+	ref, err := resolveTicket(rules, input.Description, input.Ticket)
+	if err != nil {
+		return types.TimeEntry{}, err
+	}
+
+	return types.TimeEntry{
+		Date:          input.Date,
+		Hours:         hours,
+		ProjectID:     input.ProjectID,
+		TaskID:        input.TaskID,
+		Description:   input.Description,
+		Billable:      input.Billable,
+		Tag:           input.Tag,
+		RemoteService: ref.Service,
+		RemoteID:      ref.ID,
+		RemoteURL:     ref.URL,
+	}, nil
+}
+
+// resolveTicket returns the ticket an entry refers to: the explicitly
+// entered reference if there is one, otherwise the first one detected in
+// the description
+func resolveTicket(rules []ticket.Rule, description, input string) (ticket.Ref, error) {
+	if strings.TrimSpace(input) != "" {
+		return ticket.Resolve(rules, input)
+	}
+	ref, _ := ticket.Detect(rules, description)
+	return ref, nil
+}
+
+// maxHours bounds parsed hours: no entry or weekly target can exceed a week
+const maxHours = 7 * 24
+
+// parseHours converts a string to hours, supporting both decimal and time format
+func parseHours(input string) (float64, error) {
+	// Try decimal format first (e.g. "1.5")
+	if hours, err := strconv.ParseFloat(input, 64); err == nil {
+		return checkHours(hours)
+	}
+
+	// Try time format (e.g. "1:30")
+	parts := strings.Split(input, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid format. use decimal (e.g. 1.5) or time (e.g. 1:30)")
+	}
+
+	hours, err1 := strconv.ParseFloat(parts[0], 64)
+	minutes, err2 := strconv.ParseFloat(parts[1], 64)
+	if err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("invalid format. use decimal (e.g. 1.5) or time (e.g. 1:30)")
+	}
+
+	if math.IsNaN(minutes) || minutes < 0 || minutes >= 60 {
+		return 0, fmt.Errorf("minutes must be between 0 and 59")
+	}
+
+	return checkHours(hours + (minutes / 60.0))
+}
+
+// checkHours rejects hours that are not a positive number up to maxHours.
+// ParseFloat accepts "NaN", "Inf" and values like "1e300".
+func checkHours(hours float64) (float64, error) {
+	if math.IsNaN(hours) || math.IsInf(hours, 0) {
+		return 0, fmt.Errorf("hours must be a number")
+	}
+	if hours <= 0 {
+		return 0, fmt.Errorf("hours must be greater than 0")
+	}
+	if hours > maxHours {
+		return 0, fmt.Errorf("hours must be at most %d", maxHours)
+	}
+	return hours, nil
+}
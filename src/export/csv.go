@@ -0,0 +1,98 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// Dialect describes the CSV flavour a spreadsheet expects
+type Dialect struct {
+	Comma   rune // Field separator
+	Decimal byte // Decimal separator for hours
+	BOM     bool // Start with a UTF-8 byte order mark so Excel detects the encoding
+}
+
+// DefaultDialect is plain RFC 4180 CSV
+var DefaultDialect = Dialect{Comma: ',', Decimal: '.'}
+
+// ExcelCommaDialect suits spreadsheets in locales that write decimals with a
+// comma, such as German Excel, which then expects semicolons between fields
+var ExcelCommaDialect = Dialect{Comma: ';', Decimal: ',', BOM: true}
+
+// decimalCommaLanguages use a comma as decimal separator
+var decimalCommaLanguages = map[string]bool{
+	"de": true, "fr": true, "es": true, "it": true, "nl": true, "pt": true,
+	"da": true, "sv": true, "nb": true, "nn": true, "fi": true, "pl": true,
+	"cs": true, "sk": true, "hu": true, "ru": true, "tr": true,
+}
+
+// DialectFor returns the dialect for a locale such as "de", "de_DE.UTF-8"
+// or "en-US". Unknown or empty locales get DefaultDialect.
+func DialectFor(locale string) Dialect {
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	if decimalCommaLanguages[lang] {
+		return ExcelCommaDialect
+	}
+	return DefaultDialect
+}
+
+// LocaleFromEnv returns the locale numbers are formatted in, following the
+// usual precedence of LC_ALL, LC_NUMERIC and LANG
+func LocaleFromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// FormatHours formats hours with two decimals and the dialect's separator
+func (d Dialect) FormatHours(hours float64) string {
+	s := strconv.FormatFloat(hours, 'f', 2, 64)
+	if d.Decimal != 0 && d.Decimal != '.' {
+		s = strings.Replace(s, ".", string(d.Decimal), 1)
+	}
+	return s
+}
+
+// csvHeader names the columns written by WriteCSV
+var csvHeader = []string{"id", "date", "hours", "customer", "project", "task", "description", "billable", "tag", "remote_id", "remote_url"}
+
+// WriteCSV writes entries as CSV with a header row
+func WriteCSV(w io.Writer, entries []types.TimeEntry, d Dialect) error {
+	if d.Comma == 0 {
+		d = DefaultDialect
+	}
+	if d.BOM {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return err
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = d.Comma
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		r := NewRecord(e)
+		row := []string{
+			strconv.Itoa(r.ID), r.Date, d.FormatHours(r.Hours), r.Customer, r.Project, r.Task,
+			r.Description, strconv.FormatBool(r.Billable), r.Tag, r.RemoteID, r.RemoteURL,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,152 @@
+// Package export writes time entries as CSV, JSON or iCalendar files for
+// people without Moco access.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// Formats lists the supported formats
+var Formats = []string{"csv", "json", "ndjson", "ics"}
+
+// Record is the flat, machine-readable form of a time entry
+type Record struct {
+	ID          int     `json:"id"`
+	Date        string  `json:"date"`
+	Hours       float64 `json:"hours"`
+	CustomerID  int     `json:"customer_id"`
+	Customer    string  `json:"customer"`
+	ProjectID   int     `json:"project_id"`
+	Project     string  `json:"project"`
+	TaskID      int     `json:"task_id"`
+	Task        string  `json:"task"`
+	Description string  `json:"description"`
+	Billable    bool    `json:"billable"`
+	Tag         string  `json:"tag,omitempty"`
+	RemoteID    string  `json:"remote_id,omitempty"`
+	RemoteURL   string  `json:"remote_url,omitempty"`
+	Running     bool    `json:"timer_running,omitempty"`
+}
+
+// NewRecord flattens a time entry
+func NewRecord(e types.TimeEntry) Record {
+	projectID := e.ProjectID
+	if projectID == 0 {
+		projectID = e.Project.ID
+	}
+	taskID := e.TaskID
+	if taskID == 0 {
+		taskID = e.Task.ID
+	}
+	return Record{
+		ID:          e.ID,
+		Date:        e.Date,
+		Hours:       e.Hours,
+		CustomerID:  e.Customer.ID,
+		Customer:    e.Customer.Name,
+		ProjectID:   projectID,
+		Project:     e.Project.Name,
+		TaskID:      taskID,
+		Task:        e.Task.Name,
+		Description: e.Description,
+		Billable:    e.IsBillable(),
+		Tag:         e.Tag,
+		RemoteID:    e.RemoteID,
+		RemoteURL:   e.RemoteURL,
+		Running:     e.TimerStartedAt != nil,
+	}
+}
+
+// Options control how entries are written
+type Options struct {
+	Dialect Dialect   // CSV separators, see DialectFor
+	Now     time.Time // Timestamp for iCalendar files; time.Now() if zero
+	Domain  string    // Moco domain, used to make iCalendar UIDs unique
+}
+
+// Write writes entries to w in the given format
+func Write(w io.Writer, format string, entries []types.TimeEntry, opts Options) error {
+	switch format {
+	case "csv":
+		return WriteCSV(w, entries, opts.Dialect)
+	case "json":
+		records := make([]Record, 0, len(entries))
+		for _, e := range entries {
+			records = append(records, NewRecord(e))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(NewRecord(e)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "ics":
+		return WriteICS(w, entries, opts)
+	}
+	return fmt.Errorf("unknown format %q, use one of %s", format, strings.Join(Formats, ", "))
+}
+
+// FormatFor guesses the format from a file name, e.g. "week.ics"
+func FormatFor(filename string) (string, bool) {
+	i := strings.LastIndex(filename, ".")
+	if i < 0 {
+		return "", false
+	}
+	ext := strings.ToLower(filename[i+1:])
+	for _, format := range Formats {
+		if ext == format {
+			return format, true
+		}
+	}
+	return "", false
+}
+
+// Filter selects entries by project, customer and task. Each field matches
+// an ID or a case-insensitive part of the name; empty fields match anything.
+type Filter struct {
+	Project  string
+	Customer string
+	Task     string
+}
+
+// Match reports whether e passes the filter
+func (f Filter) Match(e types.TimeEntry) bool {
+	r := NewRecord(e)
+	return matches(f.Project, r.ProjectID, r.Project) &&
+		matches(f.Customer, r.CustomerID, r.Customer) &&
+		matches(f.Task, r.TaskID, r.Task)
+}
+
+// Apply returns the entries that pass the filter
+func (f Filter) Apply(entries []types.TimeEntry) []types.TimeEntry {
+	var matched []types.TimeEntry
+	for _, e := range entries {
+		if f.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+func matches(query string, id int, name string) bool {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return true
+	}
+	if query == strconv.Itoa(id) {
+		return true
+	}
+	return strings.Contains(strings.ToLower(name), strings.ToLower(query))
+}
@@ -0,0 +1,257 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+func testEntries() []types.TimeEntry {
+	notBillable := false
+	return []types.TimeEntry{
+		{
+			ID: 1, Date: "2024-03-04", Hours: 1.5, Description: "Fix login; then test, \"quickly\"",
+			Project:  types.Project{ID: 10, Name: "Web"},
+			Task:     types.Task{ID: 20, Name: "Dev"},
+			Customer: types.Customer{ID: 30, Name: "ACME"},
+			Tag:      "bug", RemoteID: "ABC-1", RemoteURL: "https://jira.example.com/browse/ABC-1",
+		},
+		{
+			ID: 2, Date: "2024-03-04", Hours: 0.25, Description: "Standup",
+			Billable: &notBillable,
+			Project:  types.Project{ID: 11, Name: "Internal"},
+			Task:     types.Task{ID: 21, Name: "Meetings"},
+			Customer: types.Customer{ID: 31, Name: "Denwerk"},
+		},
+	}
+}
+
+func TestDialectFor(t *testing.T) {
+	tests := map[string]Dialect{
+		"":            DefaultDialect,
+		"C":           DefaultDialect,
+		"en_US.UTF-8": DefaultDialect,
+		"de":          ExcelCommaDialect,
+		"de_DE.UTF-8": ExcelCommaDialect,
+		"fr-CH":       ExcelCommaDialect,
+		"pt_BR@euro":  ExcelCommaDialect,
+	}
+	for locale, want := range tests {
+		if got := DialectFor(locale); got != want {
+			t.Errorf("DialectFor(%q) = %+v, want %+v", locale, got, want)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{"default", DefaultDialect, "" +
+			"id,date,hours,customer,project,task,description,billable,tag,remote_id,remote_url\n" +
+			"1,2024-03-04,1.50,ACME,Web,Dev,\"Fix login; then test, \"\"quickly\"\"\",true,bug,ABC-1,https://jira.example.com/browse/ABC-1\n" +
+			"2,2024-03-04,0.25,Denwerk,Internal,Meetings,Standup,false,,,\n"},
+		{"german", DialectFor("de_DE.UTF-8"), "\ufeff" +
+			"id;date;hours;customer;project;task;description;billable;tag;remote_id;remote_url\n" +
+			"1;2024-03-04;1,50;ACME;Web;Dev;\"Fix login; then test, \"\"quickly\"\"\";true;bug;ABC-1;https://jira.example.com/browse/ABC-1\n" +
+			"2;2024-03-04;0,25;Denwerk;Internal;Meetings;Standup;false;;;\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WriteCSV(&buf, testEntries(), tt.dialect); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatHours(t *testing.T) {
+	tests := []struct {
+		hours float64
+		d     Dialect
+		want  string
+	}{
+		{1.5, DefaultDialect, "1.50"},
+		{1.5, ExcelCommaDialect, "1,50"},
+		{1234.567, ExcelCommaDialect, "1234,57"},
+		{0, ExcelCommaDialect, "0,00"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.FormatHours(tt.hours); got != tt.want {
+			t.Errorf("FormatHours(%v) with %q = %q, want %q", tt.hours, tt.d.Decimal, got, tt.want)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "json", testEntries(), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records", len(records))
+	}
+
+	keys := func(m map[string]interface{}) string {
+		var names []string
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return strings.Join(names, ",")
+	}
+	tests := []struct {
+		record map[string]interface{}
+		keys   string
+	}{
+		{records[0], "billable,customer,customer_id,date,description,hours,id,project,project_id,remote_id,remote_url,tag,task,task_id"},
+		// Empty tag and ticket and a stopped timer are left out
+		{records[1], "billable,customer,customer_id,date,description,hours,id,project,project_id,task,task_id"},
+	}
+	for i, tt := range tests {
+		if got := keys(tt.record); got != tt.keys {
+			t.Errorf("record %d has %s, want %s", i, got, tt.keys)
+		}
+	}
+	if r := records[1]; r["hours"] != 0.25 || r["billable"] != false || r["project_id"] != 11.0 || r["customer"] != "Denwerk" {
+		t.Errorf("record = %v", r)
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "ndjson", testEntries(), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	for _, line := range lines {
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Errorf("line %q: %v", line, err)
+		}
+	}
+}
+
+func TestWriteICS(t *testing.T) {
+	now := time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, testEntries(), Options{Now: now, Domain: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	for _, want := range []string{
+		"UID:moco-activity-1@acme\r\n",
+		"DTSTAMP:20240305T080000Z\r\n",
+		// The entries of a day follow each other from 9:00
+		"DTSTART:20240304T090000\r\nDTEND:20240304T103000\r\n",
+		"DTSTART:20240304T103000\r\nDTEND:20240304T104500\r\n",
+		`SUMMARY:Web / Dev: Fix login\; then test\, "quickly"` + "\r\n",
+		`DESCRIPTION:ACME\n1.50h` + "\r\n",
+		`DESCRIPTION:Denwerk\n0.25h (not billable)` + "\r\n",
+		"CATEGORIES:bug\r\n",
+		"URL:https://jira.example.com/browse/ABC-1\r\n",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("missing %q in\n%s", want, unfolded)
+		}
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := map[string]string{
+		"plain":             "plain",
+		`a\b`:               `a\\b`,
+		"a,b;c":             `a\,b\;c`,
+		"one\ntwo\r\nthree": `one\ntwo\nthree`,
+		"lone\rreturn":      `lone\nreturn`,
+	}
+	for in, want := range tests {
+		if got := escapeText(in); got != want {
+			t.Errorf("escapeText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []string{
+		"SUMMARY:short",
+		"SUMMARY:" + strings.Repeat("x", 100),
+		// Two-byte characters must not be split across lines
+		"SUMMARY:" + strings.Repeat("ü", 80),
+		"DESCRIPTION:" + strings.Repeat("€", 40),
+	}
+	for _, line := range tests {
+		folded := fold(line)
+		parts := strings.Split(folded, "\r\n")
+		for i, part := range parts {
+			if len(part) > 75 {
+				t.Errorf("part of %d octets", len(part))
+			}
+			if i > 0 && !strings.HasPrefix(part, " ") {
+				t.Errorf("continuation %q doesn't start with a space", part)
+			}
+			if !utf8.ValidString(part) {
+				t.Errorf("part %q splits a character", part)
+			}
+		}
+		if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != line {
+			t.Errorf("unfolding gives %q, want %q", unfolded, line)
+		}
+	}
+}
+
+func TestFormatFor(t *testing.T) {
+	tests := map[string]string{"week.ics": "ics", "OUT.CSV": "csv", "a.ndjson": "ndjson", "report.xlsx": "", "noext": ""}
+	for name, want := range tests {
+		got, ok := FormatFor(name)
+		if got != want || ok != (want != "") {
+			t.Errorf("FormatFor(%q) = %q, %v", name, got, ok)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	entries := testEntries()
+	tests := []struct {
+		filter Filter
+		want   []int
+	}{
+		{Filter{}, []int{1, 2}},
+		{Filter{Customer: "acme"}, []int{1}},
+		{Filter{Project: "11"}, []int{2}},
+		{Filter{Task: "dev", Customer: "denwerk"}, nil},
+	}
+	for _, tt := range tests {
+		var got []int
+		for _, e := range tt.filter.Apply(entries) {
+			got = append(got, e.ID)
+		}
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("%+v selects %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// dayStart is when the first event of a day begins. Moco only stores
+// durations, so the entries of a day are laid out back to back from here.
+const dayStart = 9 * time.Hour
+
+// WriteICS writes entries as an iCalendar file with one event per entry.
+// Times are floating local times, which calendars show as booked.
+func WriteICS(w io.Writer, entries []types.TimeEntry, opts Options) error {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	domain := opts.Domain
+	if domain == "" {
+		domain = "mocoapp.com"
+	}
+
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		bw.WriteString(fold(s))
+		bw.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//moco-tui//export//EN")
+	line("CALSCALE:GREGORIAN")
+
+	next := map[string]time.Time{}
+	for _, e := range entries {
+		day, err := time.Parse("2006-01-02", e.Date)
+		if err != nil {
+			return fmt.Errorf("entry #%d has an invalid date %q", e.ID, e.Date)
+		}
+		start, ok := next[e.Date]
+		if !ok {
+			start = day.Add(dayStart)
+		}
+		duration := time.Duration(math.Round(e.Hours*60)) * time.Minute
+		next[e.Date] = start.Add(duration)
+
+		r := NewRecord(e)
+		summary := r.Project + " / " + r.Task
+		if r.Description != "" {
+			summary += ": " + r.Description
+		}
+		details := fmt.Sprintf("%s\n%.2fh", r.Customer, r.Hours)
+		if !r.Billable {
+			details += " (not billable)"
+		}
+
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:moco-activity-%d@%s", r.ID, domain))
+		line("DTSTAMP:" + now.UTC().Format("20060102T150405Z"))
+		line("DTSTART:" + start.Format("20060102T150405"))
+		line("DTEND:" + start.Add(duration).Format("20060102T150405"))
+		line("SUMMARY:" + escapeText(summary))
+		line("DESCRIPTION:" + escapeText(details))
+		if r.Tag != "" {
+			line("CATEGORIES:" + escapeText(r.Tag))
+		}
+		if r.RemoteURL != "" {
+			line("URL:" + r.RemoteURL)
+		}
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// escapeText escapes an iCalendar TEXT value
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// fold splits lines longer than 75 octets as RFC 5545 requires, without
+// breaking UTF-8 sequences
+func fold(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
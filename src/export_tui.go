@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/denwerk/moco/src/api"
+	"github.com/denwerk/moco/src/export"
+	"github.com/denwerk/moco/src/ui"
+)
+
+// entriesExportedMsg is sent when an export from the TUI was written
+type entriesExportedMsg struct {
+	name  string
+	count int
+	info  api.ListInfo
+	err   error
+}
+
+// openExportForm asks for the range, filters, format and file of an
+// export, starting from the loaded days
+func (m *Model) openExportForm() {
+	r := loadedRange(time.Now())
+	m.confirmDelete = false
+	m.exportOverwrite = ""
+	m.exportForm = ui.NewExportForm(r.From, r.To, "csv")
+	m.exportForm.SetKeyHints(m.keys.Keys("submit"), m.keys.Keys("back"), m.keys.Keys("next_pane"))
+}
+
+// handleExportKey handles keys while the export dialog is open. Like in
+// the form, typed characters always go to the focused field.
+func (m *Model) handleExportKey(msg tea.KeyMsg) tea.Cmd {
+	if isTextKey(msg) {
+		m.exportForm.Update(msg)
+		return nil
+	}
+
+	switch m.keys.Action(msg.String(), scopeGlobal) {
+	case "quit":
+		return m.quit()
+	case "back":
+		m.exportForm = nil
+		return nil
+	case "submit":
+		return m.submitExport()
+	case "up", "prev_pane":
+		m.exportForm.PrevField()
+		return nil
+	case "down", "next_pane":
+		m.exportForm.NextField()
+		return nil
+	}
+	m.exportForm.Update(msg)
+	return nil
+}
+
+// submitExport validates the dialog and writes the export in the
+// background. An existing file is only replaced if the export is submitted
+// again after the dialog warned about it.
+func (m *Model) submitExport() tea.Cmd {
+	v := m.exportForm.Values()
+	if _, ok := export.FormatFor("." + v.Format); !ok {
+		m.exportForm.SetError(fmt.Sprintf("unknown format %q, use one of %s", v.Format, strings.Join(export.Formats, ", ")))
+		return nil
+	}
+	r, err := resolveRange("", v.From, v.To, time.Now())
+	if err != nil {
+		m.exportForm.SetError(err.Error())
+		return nil
+	}
+
+	name := v.File
+	if name == "" {
+		name = fmt.Sprintf("moco-%s-%s.%s", r.From, r.To, v.Format)
+	}
+	overwrite := m.exportOverwrite == name
+	if _, err := os.Lstat(name); err == nil && !overwrite {
+		m.exportOverwrite = name
+		m.exportForm.SetError(fmt.Sprintf("%s exists, press %s again to replace it", name, m.keys.Keys("submit")))
+		return nil
+	}
+
+	m.exportForm = nil
+	m.exportOverwrite = ""
+	filter := export.Filter{Project: v.Project, Customer: v.Customer, Task: v.Task}
+	opts := exportOptions(m.cfg, export.LocaleFromEnv())
+	client := m.client
+	return func() tea.Msg {
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		entries, info, err := client.Activities(ctx, r.From, r.To)
+		if err != nil {
+			return entriesExportedMsg{name: name, err: err}
+		}
+		entries = filter.Apply(entries)
+		sortEntries(entries)
+		err = exportFile(name, v.Format, entries, opts, overwrite)
+		return entriesExportedMsg{name: name, count: len(entries), info: info, err: err}
+	}
+}
+
+func (m *Model) handleEntriesExported(msg entriesExportedMsg) {
+	switch {
+	case errors.Is(msg.err, os.ErrExist):
+		m.setMessage(fmt.Sprintf("Export not written: %s was created meanwhile", msg.name), true)
+	case msg.err != nil:
+		m.setMessage(fmt.Sprintf("Export failed: %s", describeError(msg.err)), true)
+	case msg.info.Truncated:
+		m.setMessage(fmt.Sprintf("Exported %d entries to %s, but only %d of %d were loaded, the list was truncated",
+			msg.count, msg.name, msg.info.Fetched, msg.info.Total), true)
+	default:
+		m.setMessage(fmt.Sprintf("Exported %d entries to %s", msg.count, msg.name), false)
+	}
+}
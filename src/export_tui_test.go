@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/denwerk/moco/src/export"
+	"github.com/denwerk/moco/src/mocotest"
+)
+
+func TestExportFiltersLoadedRange(t *testing.T) {
+	dir := isolate(t)
+	chdir(t, dir)
+	srv := mocotest.NewServer()
+	defer srv.Close()
+	m := newTestModel(t, srv)
+	m.focusedPane = "timeEntries"
+
+	typeKeys(m, "E", "down", "down", "down", "globex", "down", "down", "backspace", "backspace", "backspace", "json", "down", "out.json")
+	msg, _ := runCmd(t, m, typeKeys(m, "enter"))
+	if exported, ok := msg.(entriesExportedMsg); !ok || exported.err != nil {
+		t.Fatalf("export sent %#v", msg)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "out.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []export.Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Errorf("exported %d entries, want the 3 of Globex", len(records))
+	}
+	for _, r := range records {
+		if r.Customer != "Globex" {
+			t.Errorf("exported an entry of %s", r.Customer)
+		}
+	}
+}
+
+func TestExportAsksBeforeReplacingFile(t *testing.T) {
+	dir := isolate(t)
+	chdir(t, dir)
+	srv := mocotest.NewServer()
+	defer srv.Close()
+	m := newTestModel(t, srv)
+	m.focusedPane = "timeEntries"
+
+	name := filepath.Join(dir, "week.csv")
+	if err := os.WriteFile(name, []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	typeKeys(m, "E", "down", "down", "down", "down", "down", "down", "week.csv")
+	if cmd := typeKeys(m, "enter"); cmd != nil {
+		t.Fatal("existing file replaced without asking")
+	}
+	if view := m.exportForm.View(); !strings.Contains(view, "week.csv exists") {
+		t.Errorf("no warning in %q", view)
+	}
+	if data, _ := os.ReadFile(name); string(data) != "keep me" {
+		t.Fatal("file changed before confirming")
+	}
+
+	// Submitting again confirms
+	msg, _ := runCmd(t, m, typeKeys(m, "enter"))
+	if exported, ok := msg.(entriesExportedMsg); !ok || exported.err != nil || exported.count == 0 {
+		t.Fatalf("export sent %#v", msg)
+	}
+	if data, _ := os.ReadFile(name); !strings.Contains(string(data), "Daily standup") {
+		t.Errorf("file not replaced: %q", data)
+	}
+}
+
+func TestExportFileKeepsExistingFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out.csv")
+	if err := os.WriteFile(name, []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := exportFile(name, "csv", nil, export.Options{}, false); !os.IsExist(err) {
+		t.Errorf("err = %v, want it to exist", err)
+	}
+	if data, _ := os.ReadFile(name); string(data) != "keep me" {
+		t.Errorf("file replaced with %q", data)
+	}
+	if err := exportFile(name, "csv", nil, export.Options{}, true); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(name); string(data) == "keep me" {
+		t.Error("file not replaced")
+	}
+}
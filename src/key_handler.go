@@ -1,27 +1,90 @@
 package main
 
 import (
+	"fmt"
+
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func (m *Model) handleKeyMsg(msg tea.KeyMsg) tea.Cmd {
-	switch msg.String() {
-	case "esc":
-		return m.handleEscKey()
-	case "enter":
-		return m.handleEnterKey()
-	case "right":
-		return m.handleRightKey()
-	case "left":
-		return m.handleLeftKey()
-	case "up", "k":
-		return m.handleUpKey(msg)
-	case "down", "j":
-		return m.handleDownKey(msg)
-	case "tab":
-		return m.handleTabKey()
-	case "d":
-		return m.handleDKey()
+	if m.helpView {
+		// Any key closes the help, so it can't get in the way
+		m.helpView = false
+		if m.keys.Action(msg.String(), scopeGlobal) == "quit" {
+			return m.quit()
+		}
+		return nil
+	}
+	if m.exportForm != nil {
+		return m.handleExportKey(msg)
+	}
+	if m.copyForm != nil {
+		return m.handleCopyKey(msg)
+	}
+
+	// Typing in the form wins over keys bound to characters
+	if m.focusedPane == "form" && isTextKey(msg) {
+		return m.form.Update(msg)
+	}
+
+	action := m.keys.Action(msg.String(), m.keyScopes()...)
+	switch action {
+	case "quit":
+		return m.quit()
+	case "back":
+		if m.queueView && m.focusedPane == "timeEntries" {
+			m.queueView = false
+			return nil
+		}
+		return m.handleBackKey()
+	case "submit":
+		return m.handleSubmitKey()
+	case "focus_form":
+		return m.handleFocusFormKey()
+	case "focus_tasks":
+		return m.handleFocusTasksKey()
+	case "up":
+		return m.handleMoveKey(-1)
+	case "down":
+		return m.handleMoveKey(1)
+	case "next_pane":
+		return m.handleNextPaneKey()
+	case "prev_pane":
+		return m.handlePrevPaneKey()
+	case "refresh":
+		return tea.Batch(m.loadTimeEntries(), m.refreshProjects())
+	case "help":
+		m.confirmDelete = false
+		m.helpView = true
+		return nil
+	case "delete":
+		return m.handleDeleteKey()
+	case "edit":
+		return m.handleEditKey()
+	case "start_timer":
+		return m.startTimer()
+	case "toggle_timer":
+		return m.toggleTimer()
+	case "stop_timer":
+		return m.stopRunningTimer()
+	case "mark":
+		m.toggleCopyMark()
+		return nil
+	case "copy":
+		m.openCopyForm()
+		return nil
+	case "export":
+		m.openExportForm()
+		return nil
+	case "switch_profile":
+		return m.switchProfile()
+	case "queue":
+		m.queueView = !m.queueView
+		m.focusedPane = "timeEntries"
+		m.clampQueueCursor()
+		return nil
+	case "queue_retry", "queue_fix", "queue_discard":
+		return m.handleQueueKey(action)
 	}
 
 	if m.focusedPane == "form" {
@@ -35,33 +98,66 @@ func (m *Model) handleKeyMsg(msg tea.KeyMsg) tea.Cmd {
 	return nil
 }
 
-func (m *Model) handleEscKey() tea.Cmd {
+// keyScopes returns the scopes of the key bindings active in the focused
+// pane, see keyScope
+func (m *Model) keyScopes() []keyScope {
+	switch m.focusedPane {
+	case "form":
+		return []keyScope{scopeGlobal}
+	case "timeEntries":
+		if m.queueView {
+			return []keyScope{scopeQueue, scopePanes, scopeGlobal}
+		}
+		return []keyScope{scopeEntries, scopePanes, scopeGlobal}
+	}
+	return []keyScope{scopeTasks, scopePanes, scopeGlobal}
+}
+
+// isTextKey reports whether the key types text in an input
+func isTextKey(msg tea.KeyMsg) bool {
+	return (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace) && !msg.Alt
+}
+
+// applyKeyHints names the bound keys in the hints of the form
+func (m *Model) applyKeyHints() {
+	m.form.SetKeyHints(m.keys.Keys("submit"), m.keys.Keys("back"), m.keys.Keys("next_pane"))
+}
+
+func (m *Model) quit() tea.Cmd {
+	if m.ticker != nil {
+		m.ticker.Stop()
+	}
+	return tea.Quit
+}
+
+func (m *Model) handleBackKey() tea.Cmd {
 	if m.confirmDelete {
 		m.confirmDelete = false
 		m.selectedEntry = nil
 		return nil
 	}
+	if m.form.EditingID() != 0 {
+		m.cancelEdit()
+		return nil
+	}
 	if m.focusedPane != "left" {
 		m.focusedPane = "left"
 		m.blurAllInputs()
 		return nil
 	}
-	if m.ticker != nil {
-		m.ticker.Stop()
-	}
-	return tea.Quit
+	return m.quit()
 }
 
-func (m *Model) handleEnterKey() tea.Cmd {
+func (m *Model) handleSubmitKey() tea.Cmd {
 	if m.focusedPane == "form" {
-		m.handleTimeEntrySubmission()
+		return m.handleTimeEntrySubmission()
 	} else if m.confirmDelete {
-		m.handleDeleteTimeEntry()
+		return m.handleDeleteTimeEntry()
 	}
 	return nil
 }
 
-func (m *Model) handleRightKey() tea.Cmd {
+func (m *Model) handleFocusFormKey() tea.Cmd {
 	if m.focusedPane == "left" {
 		m.focusedPane = "form"
 		m.blurAllInputs()
@@ -69,46 +165,47 @@ func (m *Model) handleRightKey() tea.Cmd {
 	return nil
 }
 
-func (m *Model) handleLeftKey() tea.Cmd {
+func (m *Model) handleFocusTasksKey() tea.Cmd {
 	if m.focusedPane != "left" {
 		m.focusedPane = "left"
 	}
 	return nil
 }
 
-func (m *Model) handleUpKey(msg tea.KeyMsg) tea.Cmd {
-	if m.focusedPane == "left" {
-		var cmd tea.Cmd
-		m.taskList, cmd = m.taskList.Update(msg)
-		m.updateTaskInfo()
-		return cmd
-	} else if m.focusedPane == "form" {
-		cmd := m.form.Update(msg)
-		return cmd
-	} else if m.focusedPane == "timeEntries" {
-		m.timeEntriesTable, _ = m.timeEntriesTable.Update(msg)
-		m.updateSelectedEntry()
-	}
-	return nil
-}
-
-func (m *Model) handleDownKey(msg tea.KeyMsg) tea.Cmd {
-	if m.focusedPane == "left" {
-		var cmd tea.Cmd
-		m.taskList, cmd = m.taskList.Update(msg)
+// handleMoveKey moves the cursor of the focused pane by delta, which is -1
+// or 1
+func (m *Model) handleMoveKey(delta int) tea.Cmd {
+	switch m.focusedPane {
+	case "left":
+		if delta < 0 {
+			m.taskList.CursorUp()
+		} else {
+			m.taskList.CursorDown()
+		}
 		m.updateTaskInfo()
-		return cmd
-	} else if m.focusedPane == "form" {
-		cmd := m.form.Update(msg)
-		return cmd
-	} else if m.focusedPane == "timeEntries" {
-		m.timeEntriesTable, _ = m.timeEntriesTable.Update(msg)
+	case "form":
+		if delta < 0 {
+			m.form.PrevField()
+		} else {
+			m.form.NextField()
+		}
+	case "timeEntries":
+		if m.queueView {
+			m.queueCursor += delta
+			m.clampQueueCursor()
+			return nil
+		}
+		if delta < 0 {
+			m.timeEntriesTable.MoveUp(1)
+		} else {
+			m.timeEntriesTable.MoveDown(1)
+		}
 		m.updateSelectedEntry()
 	}
 	return nil
 }
 
-func (m *Model) handleTabKey() tea.Cmd {
+func (m *Model) handleNextPaneKey() tea.Cmd {
 	switch m.focusedPane {
 	case "left":
 		m.focusedPane = "form"
@@ -122,13 +219,58 @@ func (m *Model) handleTabKey() tea.Cmd {
 	return nil
 }
 
-func (m *Model) handleDKey() tea.Cmd {
-	if m.focusedPane == "timeEntries" && m.selectedEntry != nil {
+func (m *Model) handlePrevPaneKey() tea.Cmd {
+	switch m.focusedPane {
+	case "left":
+		m.focusedPane = "timeEntries"
+	case "form":
+		m.focusedPane = "left"
+		m.blurAllInputs()
+	case "timeEntries":
+		m.focusedPane = "form"
+		m.blurAllInputs()
+	}
+	return nil
+}
+
+func (m *Model) handleDeleteKey() tea.Cmd {
+	if m.selectedEntry != nil {
 		if !m.confirmDelete {
 			m.confirmDelete = true
 			return nil
 		}
-		m.handleDeleteTimeEntry()
+		return m.handleDeleteTimeEntry()
+	}
+	return nil
+}
+
+func (m *Model) handleEditKey() tea.Cmd {
+	if m.selectedEntry != nil {
+		m.confirmDelete = false
+		m.handleEditTimeEntry()
+	}
+	return nil
+}
+
+// handleQueueKey acts on the selected write of the queue view
+func (m *Model) handleQueueKey(action string) tea.Cmd {
+	switch action {
+	case "queue_retry":
+		if op := m.selectedQueuedOp(); op != nil {
+			if err := m.queue.Retry(op.ID); err != nil {
+				m.setMessage(fmt.Sprintf("Error saving queue: %v", err), true)
+			}
+			return m.replayQueue()
+		}
+	case "queue_discard":
+		if op := m.selectedQueuedOp(); op != nil {
+			if err := m.queue.Remove(op.ID); err != nil {
+				m.setMessage(fmt.Sprintf("Error saving queue: %v", err), true)
+			}
+			m.clampQueueCursor()
+		}
+	case "queue_fix":
+		m.fixQueuedOp()
 	}
 	return nil
 }
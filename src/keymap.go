@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/denwerk/moco/src/ui"
+)
+
+// keyScope says where the keys of an action are active
+type keyScope int
+
+const (
+	scopeGlobal  keyScope = iota // Every pane; in the form only keys that don't type text
+	scopePanes                   // Every pane but the form
+	scopeTasks                   // The task list
+	scopeEntries                 // The time entries pane
+	scopeQueue                   // The write queue, which replaces the time entries' keys while shown
+)
+
+var scopeTitles = map[keyScope]string{
+	scopeGlobal:  "Everywhere (characters are typed in the form)",
+	scopePanes:   "Outside the form",
+	scopeTasks:   "Task list",
+	scopeEntries: "Time entries",
+	scopeQueue:   "Write queue",
+}
+
+// keyAction is an action keys can be bound to
+type keyAction struct {
+	name  string // Name in the [keys] table of the config file
+	scope keyScope
+	keys  []string // Default keys
+	help  string
+}
+
+// keyActions lists every action with its default keys, in the order the
+// help view shows them
+var keyActions = []keyAction{
+	{"quit", scopeGlobal, []string{"ctrl+c"}, "quit"},
+	{"back", scopeGlobal, []string{"esc"}, "cancel, go back to the task list, quit"},
+	{"submit", scopeGlobal, []string{"enter"}, "save the form, confirm"},
+	{"next_pane", scopeGlobal, []string{"tab"}, "next pane, next field of the copy dialog"},
+	{"prev_pane", scopeGlobal, []string{"shift+tab"}, "previous pane, previous field of the copy dialog"},
+	{"up", scopeGlobal, []string{"up", "k"}, "move up"},
+	{"down", scopeGlobal, []string{"down", "j"}, "move down"},
+	{"refresh", scopeGlobal, []string{"ctrl+r"}, "reload entries and projects"},
+	{"stop_timer", scopeGlobal, []string{"x"}, "stop the running timer"},
+	{"queue", scopeGlobal, []string{"Q"}, "show or hide the write queue"},
+	{"switch_profile", scopeGlobal, []string{"P"}, "switch to the next profile"},
+	{"help", scopeGlobal, []string{"?", "f1"}, "show or hide this help"},
+	{"focus_tasks", scopePanes, []string{"left"}, "go to the task list"},
+	{"focus_form", scopePanes, []string{"right"}, "go to the form"},
+	{"start_timer", scopeTasks, []string{"s"}, "start a timer on the task"},
+	{"toggle_timer", scopeEntries, []string{"s"}, "start or stop the entry's timer"},
+	{"edit", scopeEntries, []string{"e"}, "edit the entry"},
+	{"delete", scopeEntries, []string{"d"}, "delete the entry"},
+	{"mark", scopeEntries, []string{"m"}, "mark the entry for copying"},
+	{"copy", scopeEntries, []string{"c"}, "copy marked entries or the day"},
+	{"export", scopeEntries, []string{"E"}, "export entries by range and filter"},
+	{"queue_retry", scopeQueue, []string{"r"}, "retry the write"},
+	{"queue_fix", scopeQueue, []string{"e"}, "fix the write in the form"},
+	{"queue_discard", scopeQueue, []string{"d"}, "discard the write"},
+}
+
+// namedKeys are the key names besides single characters, as Bubble Tea
+// reports them
+var namedKeys = map[string]bool{
+	"enter": true, "esc": true, "tab": true, "shift+tab": true, "backspace": true,
+	"delete": true, "insert": true, "home": true, "end": true, "pgup": true, "pgdown": true,
+	"up": true, "down": true, "left": true, "right": true, " ": true,
+	"shift+up": true, "shift+down": true, "shift+left": true, "shift+right": true,
+	"shift+home": true, "shift+end": true,
+	"ctrl+up": true, "ctrl+down": true, "ctrl+left": true, "ctrl+right": true,
+	"ctrl+home": true, "ctrl+end": true, "ctrl+pgup": true, "ctrl+pgdown": true,
+}
+
+// KeyMap holds the keys of every action
+type KeyMap struct {
+	keys   map[string][]string            // Keys by action
+	lookup map[keyScope]map[string]string // Actions by scope and key
+}
+
+// newKeyMap applies the [keys] table of the config file to the defaults.
+// An action set to an empty list is unbound. Unknown actions, invalid key
+// names and keys bound twice where both would apply are errors.
+func newKeyMap(overrides map[string][]string) (*KeyMap, error) {
+	km := &KeyMap{keys: map[string][]string{}, lookup: map[keyScope]map[string]string{}}
+	for _, action := range keyActions {
+		km.keys[action.name] = action.keys
+	}
+
+	var problems []string
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		action, ok := findKeyAction(name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown action %q", name))
+			continue
+		}
+		var keys []string
+		for _, key := range overrides[name] {
+			key = normalizeKey(key)
+			if !validKey(key) {
+				problems = append(problems, fmt.Sprintf("%s: invalid key %q", name, key))
+				continue
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 && action.name == "quit" {
+			problems = append(problems, "quit must keep at least one key")
+			continue
+		}
+		km.keys[name] = keys
+	}
+
+	// Report every pair of actions that would fight over a key
+	for i, a := range keyActions {
+		for _, b := range keyActions[i+1:] {
+			if !scopesOverlap(a.scope, b.scope) {
+				continue
+			}
+			for _, key := range km.keys[a.name] {
+				if containsString(km.keys[b.name], key) {
+					problems = append(problems, fmt.Sprintf("%q is bound to both %s and %s", displayKey(key), a.name, b.name))
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &ConfigError{"key bindings: " + strings.Join(problems, "; ")}
+	}
+
+	for _, action := range keyActions {
+		if km.lookup[action.scope] == nil {
+			km.lookup[action.scope] = map[string]string{}
+		}
+		for _, key := range km.keys[action.name] {
+			km.lookup[action.scope][key] = action.name
+		}
+	}
+	return km, nil
+}
+
+// scopesOverlap reports whether keys of both scopes can be active at once
+func scopesOverlap(a, b keyScope) bool {
+	switch {
+	case a == b:
+		return true
+	case a == scopeGlobal || b == scopeGlobal:
+		return true
+	case a == scopePanes || b == scopePanes:
+		return true
+	}
+	// The task list, time entries and queue are never active together
+	return false
+}
+
+func findKeyAction(name string) (keyAction, bool) {
+	for _, action := range keyActions {
+		if action.name == name {
+			return action, true
+		}
+	}
+	return keyAction{}, false
+}
+
+// normalizeKey accepts "space" for the space bar, which Bubble Tea calls " "
+func normalizeKey(key string) string {
+	if key == "space" {
+		return " "
+	}
+	return key
+}
+
+func displayKey(key string) string {
+	if key == " " {
+		return "space"
+	}
+	return key
+}
+
+// validKey reports whether Bubble Tea can report key: a named key, a
+// function key, a single character or ctrl+ and alt+ combinations
+func validKey(key string) bool {
+	key = strings.TrimPrefix(key, "alt+")
+	if namedKeys[key] || utf8.RuneCountInString(key) == 1 {
+		return true
+	}
+	if rest, ok := strings.CutPrefix(key, "ctrl+"); ok {
+		return len(rest) == 1 && strings.Contains("abcdefghijklmnopqrstuvwxyz@[\\]^_", rest)
+	}
+	for i := 1; i <= 20; i++ {
+		if key == fmt.Sprintf("f%d", i) {
+			return true
+		}
+	}
+	return false
+}
+
+// Action returns the action bound to key in the first of scopes that binds
+// it, or ""
+func (km *KeyMap) Action(key string, scopes ...keyScope) string {
+	for _, scope := range scopes {
+		if action, ok := km.lookup[scope][key]; ok {
+			return action
+		}
+	}
+	return ""
+}
+
+// Keys describes the keys of an action for hints, e.g. "d/delete"
+func (km *KeyMap) Keys(action string) string {
+	var keys []string
+	for _, key := range km.keys[action] {
+		keys = append(keys, displayKey(key))
+	}
+	if len(keys) == 0 {
+		return "(unbound)"
+	}
+	return strings.Join(keys, "/")
+}
+
+// Help lists the active bindings for the help view
+func (km *KeyMap) Help() []ui.HelpSection {
+	var sections []ui.HelpSection
+	for _, scope := range []keyScope{scopeGlobal, scopePanes, scopeTasks, scopeEntries, scopeQueue} {
+		section := ui.HelpSection{Title: scopeTitles[scope]}
+		for _, action := range keyActions {
+			if action.scope == scope && len(km.keys[action.name]) > 0 {
+				section.Rows = append(section.Rows, ui.HelpRow{Keys: km.Keys(action.name), Help: action.help})
+			}
+		}
+		sections = append(sections, section)
+	}
+	return sections
+}
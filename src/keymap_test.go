@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewKeyMapDefaults(t *testing.T) {
+	km, err := newKeyMap(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "s" and "d" mean different things in different panes
+	tests := []struct {
+		key    string
+		scopes []keyScope
+		want   string
+	}{
+		{"s", []keyScope{scopeTasks, scopePanes, scopeGlobal}, "start_timer"},
+		{"s", []keyScope{scopeEntries, scopePanes, scopeGlobal}, "toggle_timer"},
+		{"d", []keyScope{scopeQueue, scopePanes, scopeGlobal}, "queue_discard"},
+		{"j", []keyScope{scopeEntries, scopePanes, scopeGlobal}, "down"},
+		{"z", []keyScope{scopeEntries, scopePanes, scopeGlobal}, ""},
+	}
+	for _, tt := range tests {
+		if got := km.Action(tt.key, tt.scopes...); got != tt.want {
+			t.Errorf("Action(%q, %v) = %q, want %q", tt.key, tt.scopes, got, tt.want)
+		}
+	}
+	if got := km.Keys("help"); got != "?/f1" {
+		t.Errorf("Keys(help) = %q", got)
+	}
+}
+
+func TestNewKeyMapOverrides(t *testing.T) {
+	km, err := newKeyMap(map[string][]string{
+		"delete": {"D", "alt+d"},
+		"mark":   {"space"},
+		"export": {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := km.Action("alt+d", scopeEntries); got != "delete" {
+		t.Errorf("alt+d = %q, want delete", got)
+	}
+	if got := km.Action("d", scopeEntries); got != "" {
+		t.Errorf("d still bound to %q", got)
+	}
+	if got := km.Action(" ", scopeEntries); got != "mark" {
+		t.Errorf("space = %q, want mark", got)
+	}
+	if km.Keys("mark") != "space" || km.Keys("export") != "(unbound)" {
+		t.Errorf("Keys = %q, %q", km.Keys("mark"), km.Keys("export"))
+	}
+}
+
+func TestNewKeyMapConflicts(t *testing.T) {
+	tests := []struct {
+		overrides map[string][]string
+		problem   string
+	}{
+		// Global keys apply in every pane
+		{map[string][]string{"edit": {"x"}}, `"x" is bound to both stop_timer and edit`},
+		{map[string][]string{"focus_form": {"Q"}}, `"Q" is bound to both queue and focus_form`},
+		{map[string][]string{"copy": {"space"}, "mark": {"space"}}, `"space" is bound to both mark and copy`},
+		{map[string][]string{"quit": {}}, "quit must keep at least one key"},
+		{map[string][]string{"explode": {"b"}}, `unknown action "explode"`},
+		{map[string][]string{"edit": {"ctrl+1"}}, `edit: invalid key "ctrl+1"`},
+	}
+	for _, tt := range tests {
+		_, err := newKeyMap(tt.overrides)
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || !strings.Contains(err.Error(), tt.problem) {
+			t.Errorf("newKeyMap(%v) = %v, want a config error about %s", tt.overrides, err, tt.problem)
+		}
+	}
+
+	// Keys of panes that are never shown together don't conflict
+	if _, err := newKeyMap(map[string][]string{"start_timer": {"e"}, "queue_retry": {"m"}}); err != nil {
+		t.Errorf("keys of separate panes rejected: %v", err)
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	for _, key := range []string{"a", "Q", "?", "ü", "enter", "shift+tab", "ctrl+r", "alt+x", "alt+ctrl+a", "f12", " "} {
+		if !validKey(key) {
+			t.Errorf("validKey(%q) = false", key)
+		}
+	}
+	for _, key := range []string{"", "ab", "ctrl+1", "ctrl+enter", "f21", "hyper+a"} {
+		if validKey(key) {
+			t.Errorf("validKey(%q) = true", key)
+		}
+	}
+}
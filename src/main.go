@@ -1,23 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/denwerk/moco/src/api"
+	"github.com/denwerk/moco/src/report"
 	"github.com/denwerk/moco/src/types"
 	"github.com/denwerk/moco/src/ui"
-	"github.com/joho/godotenv"
 )
 
 type Model struct {
 	cfg              *Config
+	client           *api.Client
+	clientOptions    []api.Option // Passed to newClient again when switching profiles
 	taskID           string
 	taskTitle        string
 	projectID        string
@@ -28,52 +34,93 @@ type Model struct {
 	height           int
 	taskList         list.Model
 	timeEntriesTable table.Model
+	tableRows        []int // Maps table rows to indexes in timeEntries, -1 for non-entry rows
 	ticker           *time.Ticker
-	focusedPane      string           // "left", "form", or "timeEntries"
-	confirmDelete    bool             // Whether to show delete confirmation
+	focusedPane      string         // "left", "form", or "timeEntries"
+	confirmDelete    bool           // Whether to show delete confirmation
+	exportForm       *ui.ExportForm // Export dialog, nil when closed
+	exportOverwrite  string         // File the export dialog warned exists; submitting again replaces it
+	helpView         bool           // Whether to show the key bindings
+	keys             *KeyMap        // Bindings from the [keys] table of the config file
+	copyMarks        map[int]bool   // IDs of the entries marked for copying
+	copyForm         *ui.CopyForm   // Review dialog while copying entries
+	copySources      []types.TimeEntry
 	selectedEntry    *types.TimeEntry // Currently selected time entry
 	lastUpdate       time.Time        // When time entries were last updated
+	entriesInfo      api.ListInfo     // Pagination state of the last time entries load
 	form             ui.FormEntry
 	messageTimer     *time.Timer // Timer for clearing messages
+	retryEvents      chan api.RetryEvent
+	retryStatus      string           // Shown while the client waits to retry a request
+	runningTimer     *types.TimeEntry // Entry whose timer is running, possibly started elsewhere
+	timerTicking     bool             // Whether the one second timer tick is scheduled
+	queue            *WriteQueue      // Writes waiting for connectivity
+	replaying        bool             // Whether a queue replay is in flight
+	queueView        bool             // Whether the time entries pane shows the queue
+	queueCursor      int
+	projects         *ProjectCache // Projects shown in the task list, nil until loaded
+	projectsErr      string        // Why the last project refresh failed
 }
 
-// parseHours converts a string to hours, supporting both decimal and time format
-func parseHours(input string) (float64, error) {
-	// Try decimal format first (e.g. "1.5")
-	if hours, err := strconv.ParseFloat(input, 64); err == nil {
-		if hours <= 0 {
-			return 0, fmt.Errorf("hours must be greater than 0")
-		}
-		return hours, nil
-	}
+// projectsLoadedMsg carries the result of revalidating the project cache
+type projectsLoadedMsg struct {
+	client     *api.Client // Client that loaded them, to drop results for a previous profile
+	projects   []types.Project
+	info       api.ListInfo
+	validators api.Validators
+	err        error
+}
 
-	// Try time format (e.g. "1:30")
-	parts := strings.Split(input, ":")
-	if len(parts) != 2 {
-		return 0, fmt.Errorf("invalid format. use decimal (e.g. 1.5) or time (e.g. 1:30)")
-	}
+// queueReplayedMsg carries the outcome of replaying the write queue
+type queueReplayedMsg struct {
+	results []QueuedOp
+}
 
-	fmt.Println(parts)
+// timerMsg is sent when a timer was started or stopped
+type timerMsg struct {
+	client  *api.Client // Client that sent it, to drop results for a previous profile
+	started bool
+	err     error
+}
 
-	hours, err1 := strconv.ParseFloat(parts[0], 64)
-	minutes, err2 := strconv.ParseFloat(parts[1], 64)
-	if err1 != nil || err2 != nil {
-		return 0, fmt.Errorf("invalid format. use decimal (e.g. 1.5) or time (e.g. 1:30)")
-	}
+// timerTickMsg redraws the running timer once a second
+type timerTickMsg time.Time
 
-	if minutes < 0 || minutes >= 60 {
-		return 0, fmt.Errorf("minutes must be between 0 and 59")
-	}
+// timeEntriesLoadedMsg carries the result of an asynchronous time entries load
+type timeEntriesLoadedMsg struct {
+	client  *api.Client // Client that loaded them, to drop results for a previous profile
+	entries []types.TimeEntry
+	info    api.ListInfo
+	err     error
+}
 
-	totalHours := hours + (minutes / 60.0)
-	if totalHours <= 0 {
-		return 0, fmt.Errorf("hours must be greater than 0")
-	}
+// entrySubmittedMsg is sent when a new time entry was submitted
+type entrySubmittedMsg struct {
+	client *api.Client // Client that sent it, to drop results for a previous profile
+	entry  types.TimeEntry
+	sentAt time.Time
+	err    error
+}
+
+// entryUpdatedMsg is sent when an edited time entry was saved
+type entryUpdatedMsg struct {
+	client *api.Client // Client that sent it, to drop results for a previous profile
+	id     int
+	entry  types.TimeEntry
+	err    error
+}
 
-	return totalHours, nil
+// entryDeletedMsg is sent when a time entry was deleted
+type entryDeletedMsg struct {
+	client *api.Client // Client that sent it, to drop results for a previous profile
+	id     int
+	err    error
 }
 
-func (m *Model) handleTimeEntrySubmission() {
+// retryMsg is sent when the API client is about to retry a request
+type retryMsg api.RetryEvent
+
+func (m *Model) handleTimeEntrySubmission() tea.Cmd {
 	// Clear previous messages
 	m.errorMsg = ""
 	m.succesMsg = ""
@@ -81,74 +128,107 @@ func (m *Model) handleTimeEntrySubmission() {
 	// Validate project selection
 	if m.projectID == "" || m.taskID == "" {
 		m.setMessage("Please select a project first", true)
-		return
-	}
-
-	date, hours, description := m.form.GetValues()
-
-	// Validate hours
-	hoursFloat, err := parseHours(hours)
-	if err != nil {
-		m.setMessage(err.Error(), true)
-		return
-	}
-
-	// Validate date
-	if date == "" {
-		m.setMessage("Date is required", true)
-		return
-	}
-
-	// Validate description
-	if description == "" {
-		m.setMessage("Description is required", true)
-		return
+		return nil
 	}
 
 	projectID, err := strconv.Atoi(m.projectID)
 	if err != nil {
 		m.setMessage("Invalid project ID", true)
-		return
+		return nil
 	}
 
 	taskID, err := strconv.Atoi(m.taskID)
 	if err != nil {
 		m.setMessage("Invalid task ID", true)
-		return
+		return nil
 	}
 
-	entry := types.TimeEntry{
-		Date:        date,
-		Hours:       hoursFloat,
+	date, hours, description := m.form.GetValues()
+	billable := m.form.Billable()
+	entry, err := newTimeEntry(entryInput{
 		ProjectID:   projectID,
 		TaskID:      taskID,
+		Date:        date,
+		Hours:       hours,
 		Description: description,
+		Ticket:      m.form.Ticket(),
+		Tag:         m.form.Tag(),
+		Billable:    &billable,
+	}, m.cfg.TicketRules)
+	if err != nil {
+		m.setMessage(err.Error(), true)
+		return nil
 	}
 
-	err = submitTimeEntry(m.cfg, entry)
-	if err != nil {
-		m.setMessage(fmt.Sprintf("Error submitting time entry: %v", err), true)
-	} else {
-		m.setMessage("Time entry submitted successfully!", false)
-		m.form.Clear()
-		m.loadTimeEntries()
+	client := m.client
+	if id := m.form.EditingID(); id != 0 {
+		return func() tea.Msg {
+			ctx, cancel := requestContext()
+			defer cancel()
+
+			_, err := client.UpdateActivity(ctx, id, entry)
+			return entryUpdatedMsg{client: client, id: id, entry: entry, err: err}
+		}
+	}
+
+	return func() tea.Msg {
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		sentAt := time.Now()
+		_, err := client.CreateActivity(ctx, entry)
+		return entrySubmittedMsg{client: client, entry: entry, sentAt: sentAt, err: err}
 	}
 }
 
-func (m *Model) handleDeleteTimeEntry() {
+// handleEditTimeEntry loads the selected time entry into the form
+func (m *Model) handleEditTimeEntry() {
 	if m.selectedEntry == nil {
 		return
 	}
 
-	err := deleteTimeEntry(m.cfg, m.selectedEntry.ID)
-	if err != nil {
-		m.setMessage(fmt.Sprintf("Error deleting time entry: %v", err), true)
-	} else {
-		m.setMessage("Time entry deleted successfully!", false)
-		m.loadTimeEntries()
+	entry := *m.selectedEntry
+	m.form.EditEntry(entry)
+
+	projectID := entry.ProjectID
+	if projectID == 0 {
+		projectID = entry.Project.ID
+	}
+	taskID := entry.TaskID
+	if taskID == 0 {
+		taskID = entry.Task.ID
+	}
+	m.projectID = fmt.Sprintf("%d", projectID)
+	m.taskID = fmt.Sprintf("%d", taskID)
+	m.taskTitle = entry.Task.Name
+	m.selectTask(m.taskList.Items())
+
+	m.focusedPane = "form"
+	m.blurAllInputs()
+}
+
+// cancelEdit leaves editing mode and restores the task chosen in the task list
+func (m *Model) cancelEdit() {
+	m.form.Clear()
+	m.updateTaskInfo()
+}
+
+func (m *Model) handleDeleteTimeEntry() tea.Cmd {
+	if m.selectedEntry == nil {
+		return nil
 	}
+
+	client := m.client
+	id := m.selectedEntry.ID
 	m.confirmDelete = false
 	m.selectedEntry = nil
+
+	return func() tea.Msg {
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		return entryDeletedMsg{client: client, id: id, err: client.DeleteActivity(ctx, id)}
+	}
 }
 
 func (m *Model) Init() tea.Cmd {
@@ -158,11 +238,10 @@ func (m *Model) Init() tea.Cmd {
 
 	// Return a command that will be executed immediately
 	return tea.Batch(
-		// Initial load of time entries
-		func() tea.Msg {
-			m.loadTimeEntries()
-			return nil
-		},
+		// Initial load of time entries and projects
+		m.loadTimeEntries(),
+		m.refreshProjects(),
+		m.waitForRetryEvent(),
 		// Start the ticker
 		func() tea.Msg {
 			for range m.ticker.C {
@@ -185,9 +264,123 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.handleWindowSizeMsg(msg)
 	case string:
 		if msg == "tick" {
-			m.loadTimeEntries()
-			cmd = m.tickerCmd()
+			m.updateTaskListTitle()
+			cmd = tea.Batch(m.loadTimeEntries(), m.tickerCmd())
+		}
+	case timeEntriesLoadedMsg:
+		if msg.client != m.client {
+			break
+		}
+		m.retryStatus = ""
+		if msg.err != nil {
+			m.errorMsg = fmt.Sprintf("Error loading time entries: %s", describeError(msg.err))
+		} else {
+			m.timeEntries = msg.entries
+			m.entriesInfo = msg.info
+			m.lastUpdate = time.Now()
+			m.updateTable()
+			m.runningTimer = api.FindRunningTimer(msg.entries)
+			// Keep 'moco timer status' in step with timers started or stopped here
+			if err := syncTimerState(m.runningTimer); err != nil {
+				m.setMessage(err.Error(), true)
+			}
+			// We are online again, so flush writes made while we weren't
+			cmd = tea.Batch(m.timerTickCmd(), m.replayQueue())
+		}
+	case entrySubmittedMsg:
+		if msg.client != m.client {
+			m.dropStaleWrite(msg.err)
+			break
+		}
+		m.retryStatus = ""
+		if msg.err != nil && shouldQueue(msg.err) {
+			m.queueWrite(queuedCreate(msg.entry, msg.sentAt, msg.err), msg.err)
+			m.form.Clear()
+		} else if msg.err != nil {
+			m.setMessage(fmt.Sprintf("Error submitting time entry: %s", describeError(msg.err)), true)
+		} else {
+			m.setMessage("Time entry submitted successfully!", false)
+			m.form.Clear()
+			cmd = m.loadTimeEntries()
+		}
+	case entryUpdatedMsg:
+		if msg.client != m.client {
+			m.dropStaleWrite(msg.err)
+			break
+		}
+		m.retryStatus = ""
+		if msg.err != nil && shouldQueue(msg.err) {
+			m.queueWrite(QueuedOp{Kind: OpUpdate, EntryID: msg.id, Entry: msg.entry}, msg.err)
+			m.form.Clear()
+		} else if msg.err != nil {
+			m.setMessage(fmt.Sprintf("Error updating time entry: %s", describeError(msg.err)), true)
+		} else {
+			m.setMessage("Time entry updated successfully!", false)
+			m.form.Clear()
+			cmd = m.loadTimeEntries()
+		}
+	case entryDeletedMsg:
+		if msg.client != m.client {
+			m.dropStaleWrite(msg.err)
+			break
+		}
+		m.retryStatus = ""
+		if msg.err != nil && shouldQueue(msg.err) {
+			m.queueWrite(QueuedOp{Kind: OpDelete, EntryID: msg.id}, msg.err)
+		} else if msg.err != nil {
+			m.setMessage(fmt.Sprintf("Error deleting time entry: %s", describeError(msg.err)), true)
+		} else {
+			m.setMessage("Time entry deleted successfully!", false)
+			cmd = m.loadTimeEntries()
+		}
+	case entriesCopiedMsg:
+		cmd = m.handleEntriesCopied(msg)
+	case entriesExportedMsg:
+		m.handleEntriesExported(msg)
+	case timerMsg:
+		if msg.client != m.client {
+			m.dropStaleWrite(msg.err)
+			break
+		}
+		m.retryStatus = ""
+		action := "stopping"
+		if msg.started {
+			action = "starting"
+		}
+		var orphanErr *api.OrphanedEntryError
+		if msg.err != nil {
+			m.setMessage(fmt.Sprintf("Error %s timer: %s", action, describeError(msg.err)), true)
+			if errors.As(msg.err, &orphanErr) {
+				// Show the entry left behind, so it can be deleted
+				cmd = m.loadTimeEntries()
+			}
+		} else if msg.started {
+			m.setMessage("Timer started", false)
+			cmd = m.loadTimeEntries()
+		} else {
+			m.setMessage("Timer stopped", false)
+			cmd = m.loadTimeEntries()
 		}
+	case projectsLoadedMsg:
+		if msg.client == m.client {
+			m.handleProjectsLoaded(msg)
+		}
+	case queueReplayedMsg:
+		m.replaying = false
+		if err := m.queue.ApplyReplay(msg.results); err != nil {
+			m.setMessage(fmt.Sprintf("Error saving queue: %v", err), true)
+		}
+		m.clampQueueCursor()
+		if applied := countApplied(msg.results); applied > 0 {
+			m.setMessage(fmt.Sprintf("Replayed %d queued write(s)", applied), false)
+			cmd = m.loadTimeEntries()
+		}
+	case timerTickMsg:
+		m.timerTicking = false
+		cmd = m.timerTickCmd()
+	case retryMsg:
+		m.retryStatus = api.RetryEvent(msg).String()
+		cmd = m.waitForRetryEvent()
 	}
 
 	// Check if message timer has expired
@@ -250,17 +443,16 @@ func (m *Model) updateTaskInfo() {
 	m.projectID = fmt.Sprintf("%d", selectedItem.ProjectID)
 	m.taskTitle = selectedItem.Desc
 	m.form.SetTaskTitle(m.taskTitle)
+	// An edited entry keeps its own flag when moved to another task
+	if m.form.EditingID() == 0 {
+		m.form.SetBillable(selectedItem.Billable)
+	}
 }
 
 func (m *Model) updateSelectedEntry() {
 	cursor := m.timeEntriesTable.Cursor()
-	if cursor > 0 {
-		for i := range m.timeEntries {
-			if i == cursor {
-				m.selectedEntry = &m.timeEntries[i]
-				break
-			}
-		}
+	if cursor >= 0 && cursor < len(m.tableRows) && m.tableRows[cursor] >= 0 {
+		m.selectedEntry = &m.timeEntries[m.tableRows[cursor]]
 	} else {
 		m.selectedEntry = nil
 	}
@@ -279,6 +471,288 @@ func (m *Model) tickerCmd() tea.Cmd {
 	}
 }
 
+// refreshProjects revalidates the assigned projects in the background
+func (m *Model) refreshProjects() tea.Cmd {
+	client := m.client
+	var validators api.Validators
+	if m.projects != nil {
+		validators = m.projects.Validators
+	}
+
+	return func() tea.Msg {
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		projects, info, fresh, err := client.AssignedProjectsIfChanged(ctx, validators)
+		return projectsLoadedMsg{client: client, projects: projects, info: info, validators: fresh, err: err}
+	}
+}
+
+func (m *Model) handleProjectsLoaded(msg projectsLoadedMsg) {
+	switch {
+	case errors.Is(msg.err, api.ErrNotModified) && m.projects != nil:
+		m.projects.FetchedAt = time.Now()
+		m.projectsErr = ""
+	case msg.err != nil:
+		m.projectsErr = describeError(msg.err)
+		m.updateTaskListTitle()
+		return
+	default:
+		m.projects = &ProjectCache{
+			Account:    m.client.BaseURL(),
+			FetchedAt:  time.Now(),
+			Validators: msg.validators,
+			Info:       msg.info,
+			Projects:   msg.projects,
+		}
+		m.projectsErr = ""
+
+		items := ui.MapProjectsToItems(msg.projects)
+		m.taskList.SetItems(items)
+		m.selectTask(items)
+	}
+
+	if err := SaveProjectCache(m.projects); err != nil {
+		log.Printf("Error saving project cache: %v", err)
+	}
+	m.updateTaskListTitle()
+}
+
+// updateTaskListTitle shows the account and how fresh the project list is
+func (m *Model) updateTaskListTitle() {
+	title := "MOCO " + m.cfg.MocoDomain + " - Select a task:"
+	if m.cfg.Profile != "" {
+		title = fmt.Sprintf("MOCO %s (%s) - Select a task:", m.cfg.MocoDomain, m.cfg.Profile)
+	}
+	switch {
+	case m.projects == nil && m.projectsErr != "":
+		title += " (error loading projects: " + m.projectsErr + ")"
+	case m.projects == nil:
+		title += " (loading projects...)"
+	default:
+		if m.projects.Info.Truncated {
+			title += fmt.Sprintf(" (%d of %d projects)", m.projects.Info.Fetched, m.projects.Info.Total)
+		}
+		if m.projectsErr != "" {
+			title += fmt.Sprintf(" (cached %s, refresh failed)", formatAge(m.projects.Age(time.Now())))
+		} else if age := m.projects.Age(time.Now()); age >= time.Minute {
+			title += fmt.Sprintf(" (cached %s)", formatAge(age))
+		}
+	}
+	m.taskList.Title = title
+}
+
+// queueWrite stores a write that failed for lack of connectivity
+func (m *Model) queueWrite(op QueuedOp, err error) {
+	op.Error = describeError(err)
+	if qerr := m.queue.Enqueue(op); qerr != nil {
+		m.setMessage(fmt.Sprintf("Error queueing %s: %v (original error: %s)", op.Kind, qerr, describeError(err)), true)
+		return
+	}
+	pending, _ := m.queue.Counts()
+	m.setMessage(fmt.Sprintf("Offline: %s queued, %d write(s) waiting", op.Kind, pending), false)
+}
+
+// replayQueue sends queued writes in the background
+func (m *Model) replayQueue() tea.Cmd {
+	if m.queue == nil || m.replaying {
+		return nil
+	}
+	ops := m.queue.Pending()
+	if len(ops) == 0 {
+		return nil
+	}
+
+	m.replaying = true
+	client := m.client
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(len(ops))*requestTimeout)
+		defer cancel()
+
+		return queueReplayedMsg{results: replayOps(ctx, client, ops)}
+	}
+}
+
+func countApplied(results []QueuedOp) int {
+	applied := 0
+	for _, op := range results {
+		if op.Status == "" {
+			applied++
+		}
+	}
+	return applied
+}
+
+func (m *Model) clampQueueCursor() {
+	if m.queueCursor >= len(m.queue.Ops) {
+		m.queueCursor = len(m.queue.Ops) - 1
+	}
+	if m.queueCursor < 0 {
+		m.queueCursor = 0
+	}
+}
+
+// selectedQueuedOp returns the op under the queue cursor, or nil
+func (m *Model) selectedQueuedOp() *QueuedOp {
+	if m.queueCursor < 0 || m.queueCursor >= len(m.queue.Ops) {
+		return nil
+	}
+	return &m.queue.Ops[m.queueCursor]
+}
+
+// fixQueuedOp moves a queued write into the form so the user can correct it
+func (m *Model) fixQueuedOp() {
+	op := m.selectedQueuedOp()
+	if op == nil || op.Kind == OpDelete {
+		return
+	}
+
+	entry := op.Entry
+	if op.Kind == OpUpdate && op.Status != OpConflict {
+		entry.ID = op.EntryID
+	} else {
+		entry.ID = 0
+	}
+	m.form.EditEntry(entry)
+	m.projectID = fmt.Sprintf("%d", entry.ProjectID)
+	m.taskID = fmt.Sprintf("%d", entry.TaskID)
+	m.selectTask(m.taskList.Items())
+
+	if err := m.queue.Remove(op.ID); err != nil {
+		m.setMessage(fmt.Sprintf("Error saving queue: %v", err), true)
+	}
+	m.clampQueueCursor()
+	m.queueView = false
+	m.focusedPane = "form"
+	m.blurAllInputs()
+}
+
+// queueStatus summarises the write queue for the time entries header
+func (m *Model) queueStatus() string {
+	if m.queue == nil {
+		return ""
+	}
+	pending, failed := m.queue.Counts()
+	if pending == 0 && failed == 0 {
+		return ""
+	}
+	status := fmt.Sprintf("Queue: %d pending", pending)
+	if failed > 0 {
+		status += fmt.Sprintf(", %d need attention", failed)
+	}
+	if m.replaying {
+		status += " (replaying...)"
+	}
+	return ui.QueuePendingStyle.Render(fmt.Sprintf("%s - press '%s' to review", status, m.keys.Keys("queue")))
+}
+
+// timerTickCmd schedules the next redraw while a timer is running
+func (m *Model) timerTickCmd() tea.Cmd {
+	if m.runningTimer == nil || m.timerTicking {
+		return nil
+	}
+	m.timerTicking = true
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return timerTickMsg(t)
+	})
+}
+
+// startTimer books an empty entry on the selected task for today and starts its timer
+func (m *Model) startTimer() tea.Cmd {
+	if m.projectID == "" || m.taskID == "" {
+		m.setMessage("Please select a project first", true)
+		return nil
+	}
+
+	projectID, err := strconv.Atoi(m.projectID)
+	if err != nil {
+		m.setMessage("Invalid project ID", true)
+		return nil
+	}
+
+	taskID, err := strconv.Atoi(m.taskID)
+	if err != nil {
+		m.setMessage("Invalid task ID", true)
+		return nil
+	}
+
+	_, _, description := m.form.GetValues()
+	billable := m.form.Billable()
+	entry := types.TimeEntry{
+		Date:        time.Now().Format("2006-01-02"),
+		Hours:       0,
+		ProjectID:   projectID,
+		TaskID:      taskID,
+		Description: description,
+		Billable:    &billable,
+		Tag:         m.form.Tag(),
+	}
+
+	client := m.client
+	return func() tea.Msg {
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		_, err := client.StartNewTimer(ctx, entry)
+		return timerMsg{client: client, started: true, err: err}
+	}
+}
+
+// toggleTimer starts or stops the timer of the selected time entry
+func (m *Model) toggleTimer() tea.Cmd {
+	if m.selectedEntry == nil {
+		return nil
+	}
+
+	client := m.client
+	entry := *m.selectedEntry
+	if entry.TimerStartedAt == nil && entry.Date != time.Now().Format("2006-01-02") {
+		m.setMessage("Timers can only run on today's entries", true)
+		return nil
+	}
+
+	return func() tea.Msg {
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		if entry.TimerStartedAt != nil {
+			_, err := client.StopTimer(ctx, entry.ID)
+			return timerMsg{client: client, started: false, err: err}
+		}
+		_, err := client.StartTimer(ctx, entry.ID)
+		return timerMsg{client: client, started: true, err: err}
+	}
+}
+
+// stopRunningTimer stops whichever timer is running, wherever it was started
+func (m *Model) stopRunningTimer() tea.Cmd {
+	if m.runningTimer == nil {
+		m.setMessage("No timer running", true)
+		return nil
+	}
+
+	client := m.client
+	id := m.runningTimer.ID
+	return func() tea.Msg {
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		_, err := client.StopTimer(ctx, id)
+		return timerMsg{client: client, started: false, err: err}
+	}
+}
+
+// waitForRetryEvent forwards the next retry notification from the API client
+func (m *Model) waitForRetryEvent() tea.Cmd {
+	if m.retryEvents == nil {
+		return nil
+	}
+	events := m.retryEvents
+	return func() tea.Msg {
+		return retryMsg(<-events)
+	}
+}
+
 func (m Model) View() string {
 	// Calculate pane widths
 	leftWidth := m.width / 2
@@ -306,6 +780,14 @@ func (m Model) View() string {
 		)
 	}
 
+	// Show pending retries so a slow request doesn't look like a hang
+	if m.retryStatus != "" {
+		formContent = lipgloss.JoinVertical(lipgloss.Left,
+			formContent,
+			ui.LastUpdateStyle.Render(fmt.Sprintf("\n%s", m.retryStatus)),
+		)
+	}
+
 	// Add success message if present
 	if m.succesMsg != "" {
 		formContent = lipgloss.JoinVertical(lipgloss.Left,
@@ -323,22 +805,44 @@ func (m Model) View() string {
 
 	// Time Entries Section
 	timeEntriesTitle := ui.TitleStyle.Render("Time Entries")
-	lastUpdate := ui.LastUpdateStyle.Render(fmt.Sprintf("Last updated: %s", m.lastUpdate.Format("15:04:05")))
+	lastUpdateText := fmt.Sprintf("Last updated: %s", m.lastUpdate.Format("15:04:05"))
+	if m.entriesInfo.Truncated {
+		lastUpdateText += fmt.Sprintf(" - showing %d of %d entries", m.entriesInfo.Fetched, m.entriesInfo.Total)
+	}
+	lastUpdateText += " - " + m.weekProgress(time.Now())
+	lastUpdate := ui.LastUpdateStyle.Render(lastUpdateText)
 
 	// Add selected entry ID to header if one is selected
 	selectedInfo := ""
 	if m.selectedEntry != nil {
 		selectedInfo = fmt.Sprintf(" (Selected: #%d)", m.selectedEntry.ID)
+		if m.selectedEntry.RemoteURL != "" {
+			selectedInfo += " " + m.selectedEntry.RemoteURL
+		}
 	}
 	header := lipgloss.JoinVertical(lipgloss.Left,
 		timeEntriesTitle,
 		lastUpdate,
+		ui.RenderTimer(m.runningTimer, time.Now()),
+		m.queueStatus(),
 		ui.SelectedStyle.Render(selectedInfo),
 	)
 
+	body := m.timeEntriesTable.View()
+	if m.queueView {
+		body = ui.RenderQueue(m.queueItems(), m.queueCursor, fmt.Sprintf("'%s' retry, '%s' fix in form, '%s' discard, '%s' close",
+			m.keys.Keys("queue_retry"), m.keys.Keys("queue_fix"), m.keys.Keys("queue_discard"), m.keys.Keys("queue")))
+	}
+	if m.copyForm != nil {
+		body = m.copyForm.View()
+	}
+	if m.exportForm != nil {
+		body = m.exportForm.View()
+	}
+
 	timeEntriesContent := lipgloss.JoinVertical(lipgloss.Left,
 		header,
-		m.timeEntriesTable.View(),
+		body,
 	)
 
 	timeEntriesStyle := ui.PaneStyle.Width(rightWidth).Height(m.height/2 - 2) // Make time entries pane take up half the height
@@ -362,44 +866,67 @@ func (m Model) View() string {
 					"Date: %s\n"+
 					"Hours: %.2f\n"+
 					"Description: %s\n\n"+
-					"Press ENTER to confirm, ESC to cancel",
+					"Press %s to confirm, %s to cancel",
 				m.selectedEntry.Date,
 				m.selectedEntry.Hours,
 				m.selectedEntry.Description,
+				m.keys.Keys("submit"), m.keys.Keys("back"),
 			))
 		rightPane = lipgloss.JoinVertical(lipgloss.Left, confirmDialog, rightPane)
 	}
 
 	// Layout
 	layout := lipgloss.JoinHorizontal(lipgloss.Left, leftPane, rightPane)
+	if m.helpView {
+		layout = ui.PaneStyle.Render(ui.RenderHelp(m.keys.Help(), m.keys.Keys("help")))
+	}
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, layout)
 }
 
-func (m *Model) loadTimeEntries() {
-	entries, err := fetchTimeEntries(m.cfg, time.Now().Format("2006-01-02"))
-	if err != nil {
-		m.errorMsg = fmt.Sprintf("Error loading time entries: %v", err)
-	} else {
-		m.timeEntries = entries
-		m.lastUpdate = time.Now()
-		m.updateTable()
+// weekProgress sums up the current week, which the loaded seven days always
+// cover up to today
+func (m *Model) weekProgress(now time.Time) string {
+	week, _ := presetRange("this-week", now)
+	summary := report.Summarize(m.timeEntries, week.From, week.To)
+	return fmt.Sprintf("this week %.2fh of %.2fh", summary.Hours, m.cfg.WeeklyTarget)
+}
+
+// loadedRange is the range of days the time entries pane shows
+func loadedRange(now time.Time) dateRange {
+	r, _ := presetRange("last-7-days", now)
+	return r
+}
+
+// loadTimeEntries fetches the last seven days including today in the background
+func (m *Model) loadTimeEntries() tea.Cmd {
+	client := m.client
+	return func() tea.Msg {
+		ctx, cancel := requestContext()
+		defer cancel()
+
+		r := loadedRange(time.Now())
+		entries, info, err := client.Activities(ctx, r.From, r.To)
+		return timeEntriesLoadedMsg{client: client, entries: entries, info: info, err: err}
 	}
 }
 
+func (m *Model) queueItems() []ui.QueueItem {
+	var items []ui.QueueItem
+	for _, op := range m.queue.Ops {
+		items = append(items, ui.QueueItem{Summary: op.Summary(), Status: op.Status, Error: op.Error})
+	}
+	return items
+}
+
 func (m *Model) updateTable() {
-	m.timeEntriesTable = ui.CreateTimeEntriesTable(m.timeEntries, 20)
+	cursor := m.timeEntriesTable.Cursor()
+	m.timeEntriesTable, m.tableRows = ui.CreateTimeEntriesTable(m.timeEntries, m.copyMarks, 20)
 
-	// Set the selected entry based on the current selection
-	if row := m.timeEntriesTable.SelectedRow(); len(row) > 0 {
-		for i, entry := range m.timeEntries {
-			if fmt.Sprintf("%d", entry.ID) == row[0] {
-				m.selectedEntry = &m.timeEntries[i]
-				break
-			}
-		}
-	} else {
-		m.selectedEntry = nil
+	// Keep the cursor where it was across refreshes
+	if cursor > 0 && cursor < len(m.tableRows) {
+		m.timeEntriesTable.SetCursor(cursor)
 	}
+	m.updateSelectedEntry()
 }
 
 func (m *Model) saveLastTask() {
@@ -440,49 +967,110 @@ func (m *Model) loadLastTask() {
 }
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
+	if code, ok := runCommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
+	demo := flag.Bool("demo", false, "run against a built-in fake Moco account instead of the real API")
+	registerConfigFlags(flag.CommandLine)
+	flag.Usage = func() {
+		printCommands(flag.CommandLine.Output())
 	}
+	flag.Parse()
 
 	if err := InitLogger(); err != nil {
 		log.Fatal("Error initializing logger:", err)
 	}
 	defer Close()
 
-	cfg, err := LoadConfig()
+	var cfg *Config
+	var err error
+	if *demo {
+		var stop func()
+		cfg, stop, err = startDemo()
+		if err != nil {
+			log.Fatal("Error starting demo:", err)
+		}
+		defer stop()
+	} else {
+		cfg, err = LoadConfig()
+		if err != nil {
+			os.Exit(fail(err))
+		}
+	}
+	// The TUI owns the terminal from here on, so it can't ask for passphrases
+	passphrasePrompt = nil
+
+	keys, err := newKeyMap(cfg.Keys)
+	if err != nil {
+		os.Exit(fail(err))
+	}
+	// Before the TUI starts, as detecting the background asks the terminal
+	theme, _, err := loadTheme(cfg)
+	if err != nil {
+		os.Exit(fail(err))
+	}
+	ui.ApplyTheme(theme)
+
+	retryEvents := make(chan api.RetryEvent, 8)
+	clientOptions := []api.Option{api.WithRetryNotify(func(event api.RetryEvent) {
+		// Never block the request on a busy UI
+		select {
+		case retryEvents <- event:
+		default:
+		}
+	})}
+	client, err := newClient(cfg, clientOptions...)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatal("Error creating API client:", err)
 	}
 
-	projects, err := fetchProjects(cfg)
+	// Start from the cached projects; they are revalidated in the background
+	cache, err := LoadProjectCache(client.BaseURL())
 	if err != nil {
-		log.Fatal("Error fetching projects:", err)
+		log.Printf("Error loading project cache: %v", err)
 	}
 
-	model := newModel(cfg, projects)
+	model := newModel(cfg, client, cache, keys)
+	model.retryEvents = retryEvents
+	model.clientOptions = clientOptions
 	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	p.Run()
 }
 
-func newModel(cfg *Config, projects []types.Project) *Model {
-	items := ui.MapProjectsToItems(projects)
-	taskList := list.New(items, ui.ItemDelegate{}, 0, 0)
-	taskList.Title = "MOCO " + cfg.MocoDomain + " - Select a task:"
+func newModel(cfg *Config, client *api.Client, cache *ProjectCache, keys *KeyMap) *Model {
+	var items []list.Item
+	if cache != nil {
+		items = ui.MapProjectsToItems(cache.Projects)
+	}
+	taskList := ui.NewTaskList(items)
+
+	queue, err := LoadWriteQueue()
+	if err != nil {
+		log.Printf("Error loading write queue: %v", err)
+		queue = &WriteQueue{}
+	}
 
 	model := &Model{
 		cfg:      cfg,
+		client:   client,
 		taskList: taskList,
 		form:     ui.NewFormEntry(),
+		queue:    queue,
+		projects: cache,
+		keys:     keys,
 	}
+	model.applyKeyHints()
+	model.updateTaskListTitle()
 
 	model.loadLastTask()
-	model.selectLastTask(items)
-	model.loadTimeEntries()
+	model.selectTask(items)
 
 	return model
 }
 
-func (m *Model) selectLastTask(items []list.Item) {
+// selectTask moves the task list cursor to the current project and task
+func (m *Model) selectTask(items []list.Item) {
 	if m.projectID == "" || m.taskID == "" {
 		return
 	}
@@ -492,6 +1080,11 @@ func (m *Model) selectLastTask(items []list.Item) {
 			if fmt.Sprintf("%d", taskItem.ProjectID) == m.projectID &&
 				fmt.Sprintf("%d", taskItem.TaskID) == m.taskID {
 				m.taskList.Select(i)
+				m.taskTitle = taskItem.Desc
+				m.form.SetTaskTitle(m.taskTitle)
+				if m.form.EditingID() == 0 {
+					m.form.SetBillable(taskItem.Billable)
+				}
 				break
 			}
 		}
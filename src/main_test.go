@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/denwerk/moco/src/api"
+	"github.com/denwerk/moco/src/mocotest"
+)
+
+// isolate points the config file lookup and the state directory at an
+// empty temporary directory and clears the settings from the environment,
+// so tests see neither the user's setup nor each other's. It returns the
+// directory.
+func isolate(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("MOCO_CONFIG_DIR", filepath.Join(dir, "state"))
+	for _, name := range append([]string{"MOCO_CONFIG_FILE", "MOCO_PROFILE", "NO_COLOR"}, settings...) {
+		t.Setenv(name, "")
+	}
+
+	reset := func() {
+		configFlags = ConfigFlags{}
+		stateProfile = ""
+	}
+	reset()
+	t.Cleanup(reset)
+	return dir
+}
+
+// chdir changes into dir until the test ends
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// newTestModel returns a TUI model talking to srv, with the form focused
+// and the first task of the demo account selected
+func newTestModel(t *testing.T, srv *mocotest.Server) *Model {
+	t.Helper()
+	cfg := &Config{MocoDomain: "test", MocoAPIKey: srv.APIKey(), MocoBaseURL: srv.URL()}
+	client, err := newClient(cfg, api.WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := newKeyMap(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := newModel(cfg, client, nil, keys)
+	m.focusedPane = "form"
+	m.projectID, m.taskID = "1000", "2000"
+	return m
+}
+
+// typeKeys sends keys to the model as if typed, e.g. "down", "backspace" or
+// "1.5"; all other words are typed as text
+func typeKeys(m *Model, keys ...string) tea.Cmd {
+	var cmd tea.Cmd
+	for _, key := range keys {
+		var msg tea.KeyMsg
+		switch key {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "backspace":
+			msg = tea.KeyMsg{Type: tea.KeyBackspace}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		}
+		_, cmd = m.Update(msg)
+	}
+	return cmd
+}
+
+// runCmd runs cmd and passes its message to the model, returning the
+// message and the model's next command
+func runCmd(t *testing.T, m *Model, cmd tea.Cmd) (tea.Msg, tea.Cmd) {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("no command to run")
+	}
+	msg := cmd()
+	_, next := m.Update(msg)
+	return msg, next
+}
@@ -0,0 +1,113 @@
+package mocotest
+
+import (
+	"time"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// DemoAPIKey is accepted by servers created without WithAPIKey
+const DemoAPIKey = "demo-api-key"
+
+// DemoUserID is the user the demo account's API key belongs to
+const DemoUserID = 933589
+
+// SeedProjects returns the assigned projects of the demo account
+func SeedProjects() []types.Project {
+	acme := types.Customer{ID: 100, Name: "ACME Corp"}
+	globex := types.Customer{ID: 101, Name: "Globex"}
+	internal := types.Customer{ID: 102, Name: "Denwerk"}
+	budget := func(amount float64) *float64 { return &amount }
+
+	return []types.Project{
+		{
+			ID: 1000, Identifier: "P-1000", Name: "ACME Website", Billable: true, Customer: acme,
+			Budget: budget(24000), Currency: "EUR",
+			Tasks: []types.Task{
+				{ID: 2000, Name: "Development", Billable: true},
+				{ID: 2001, Name: "Design", Billable: true},
+				{ID: 2002, Name: "Project Management", Billable: false},
+			},
+		},
+		{
+			ID: 1001, Identifier: "P-1001", Name: "Globex Mobile App", Billable: true, Customer: globex,
+			Budget: budget(60000), Currency: "EUR",
+			Tasks: []types.Task{
+				{ID: 2010, Name: "Development", Billable: true},
+				{ID: 2011, Name: "Code Review", Billable: true},
+			},
+		},
+		{
+			ID: 1002, Identifier: "P-1002", Name: "Internal", Billable: false, Customer: internal,
+			Currency: "EUR",
+			Tasks: []types.Task{
+				{ID: 2020, Name: "Standup", Billable: false},
+				{ID: 2021, Name: "Training", Billable: false},
+			},
+		},
+	}
+}
+
+// SeedActivities returns a week of bookings ending on the day of now
+func SeedActivities(now time.Time) []types.TimeEntry {
+	type booking struct {
+		daysAgo     int
+		projectID   int
+		taskID      int
+		hours       float64
+		description string
+	}
+	bookings := []booking{
+		{0, 1002, 2020, 0.25, "Daily standup"},
+		{0, 1000, 2000, 2.5, "ABC-101 checkout page"},
+		{1, 1002, 2020, 0.25, "Daily standup"},
+		{1, 1000, 2000, 4, "ABC-98 product search"},
+		{1, 1001, 2011, 1.5, "Review push notifications"},
+		{2, 1002, 2020, 0.25, "Daily standup"},
+		{2, 1001, 2010, 6, "Offline sync"},
+		{3, 1000, 2001, 3, "Landing page mockups"},
+		{3, 1000, 2002, 1, "Sprint planning"},
+		{4, 1002, 2021, 2, "Go workshop"},
+		{6, 1001, 2010, 5, "Login flow"},
+	}
+
+	projects := SeedProjects()
+	var entries []types.TimeEntry
+	for i, b := range bookings {
+		entry := types.TimeEntry{
+			ID:          5000 + i,
+			Date:        now.AddDate(0, 0, -b.daysAgo).Format("2006-01-02"),
+			Hours:       b.hours,
+			ProjectID:   b.projectID,
+			TaskID:      b.taskID,
+			Description: b.description,
+		}
+		resolve(&entry, projects)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// resolve fills in the nested project, task and customer of an entry like
+// the API does in its responses
+func resolve(entry *types.TimeEntry, projects []types.Project) bool {
+	for _, project := range projects {
+		if project.ID != entry.ProjectID {
+			continue
+		}
+		for _, task := range project.Tasks {
+			if task.ID != entry.TaskID {
+				continue
+			}
+			entry.Project = types.Project{ID: project.ID, Name: project.Name, Billable: project.Billable}
+			entry.Task = task
+			entry.Customer = project.Customer
+			if entry.Billable == nil {
+				billable := project.Billable && task.Billable
+				entry.Billable = &billable
+			}
+			return true
+		}
+	}
+	return false
+}
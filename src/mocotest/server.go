@@ -0,0 +1,417 @@
+// Package mocotest provides an in-process fake of the Moco API for tests
+// and demo mode. It covers the endpoints the client uses and keeps all
+// data in memory.
+package mocotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// DefaultPerPage is the page size used when a request doesn't ask for one
+const DefaultPerPage = 100
+
+// Server is a fake Moco account
+type Server struct {
+	mu         sync.Mutex
+	apiKey     string
+	perPage    int
+	now        func() time.Time
+	projects   []types.Project
+	activities map[int]*types.TimeEntry
+	nextID     int
+	faults     []Fault
+	requests   []string
+	http       *httptest.Server
+}
+
+// Fault makes the server fail requests instead of answering them normally,
+// to exercise retries and recovery from lost responses
+type Fault struct {
+	Method     string // Matches any method if empty
+	Path       string // Path below the API root, e.g. "activities"; matches any if empty
+	Status     int    // Status to answer with; 0 closes the connection without an answer
+	RetryAfter string // Value of the Retry-After header, if any
+	Apply      bool   // Handle the request before failing, as if only the answer was lost
+	Count      int    // Number of requests to fail, 1 if 0
+}
+
+func (f Fault) matches(method, path string) bool {
+	return (f.Method == "" || f.Method == method) && (f.Path == "" || f.Path == path)
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithAPIKey sets the API key the server accepts
+func WithAPIKey(key string) Option {
+	return func(s *Server) { s.apiKey = key }
+}
+
+// WithPerPage sets the default page size, e.g. to exercise pagination
+func WithPerPage(n int) Option {
+	return func(s *Server) { s.perPage = n }
+}
+
+// WithClock replaces time.Now for timers, seed data and the Date header
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) { s.now = now }
+}
+
+// WithProjects replaces the seeded projects
+func WithProjects(projects []types.Project) Option {
+	return func(s *Server) { s.projects = projects }
+}
+
+// WithActivities replaces the seeded activities
+func WithActivities(entries []types.TimeEntry) Option {
+	return func(s *Server) {
+		s.activities = make(map[int]*types.TimeEntry)
+		for i := range entries {
+			entry := entries[i]
+			s.activities[entry.ID] = &entry
+			if entry.ID >= s.nextID {
+				s.nextID = entry.ID + 1
+			}
+		}
+	}
+}
+
+// NewServer starts a fake Moco API seeded with demo data. Call Close when done.
+func NewServer(opts ...Option) *Server {
+	s := NewUnstartedServer(opts...)
+	s.http = httptest.NewServer(s)
+	return s
+}
+
+// NewUnstartedServer returns a seeded fake to be served by the caller
+func NewUnstartedServer(opts ...Option) *Server {
+	s := &Server{
+		apiKey:  DemoAPIKey,
+		perPage: DefaultPerPage,
+		now:     time.Now,
+		nextID:  1,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.projects == nil {
+		s.projects = SeedProjects()
+	}
+	if s.activities == nil {
+		WithActivities(SeedActivities(s.now()))(s)
+	}
+	return s
+}
+
+// URL returns the API root to pass to api.WithBaseURL
+func (s *Server) URL() string {
+	return s.http.URL + "/api/v1/"
+}
+
+// APIKey returns the key the server accepts
+func (s *Server) APIKey() string {
+	return s.apiKey
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	if s.http != nil {
+		s.http.Close()
+	}
+}
+
+// Fail makes the next matching requests fail as f describes. Faults are
+// used up in the order they were added.
+func (s *Server) Fail(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f.Count < 1 {
+		f.Count = 1
+	}
+	s.faults = append(s.faults, f)
+}
+
+// Requests returns the requests served so far, as "GET activities"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+// Activities returns a snapshot of all stored activities sorted by ID
+func (s *Server) Activities() []types.TimeEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []types.TimeEntry
+	for _, entry := range s.activities {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Token "+s.apiKey {
+		writeError(w, http.StatusUnauthorized, "invalid API key")
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1"), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The Date header and created_at follow the clock of the fake, like
+	// Moco's follow its own
+	w.Header().Set("Date", s.now().UTC().Format(http.TimeFormat))
+	s.requests = append(s.requests, r.Method+" "+path)
+	if fault, ok := s.takeFault(r.Method, path); ok {
+		s.fail(w, r, path, fault)
+		return
+	}
+	s.route(w, r, path)
+}
+
+// takeFault returns the first fault matching the request and uses it up
+func (s *Server) takeFault(method, path string) (Fault, bool) {
+	for i, f := range s.faults {
+		if !f.matches(method, path) {
+			continue
+		}
+		s.faults[i].Count--
+		if s.faults[i].Count == 0 {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		}
+		return f, true
+	}
+	return Fault{}, false
+}
+
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, path string, f Fault) {
+	if f.Apply {
+		s.route(httptest.NewRecorder(), r, path)
+	}
+	if f.Status == 0 {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		f.Status = http.StatusBadGateway
+	}
+	if f.RetryAfter != "" {
+		w.Header().Set("Retry-After", f.RetryAfter)
+	}
+	writeError(w, f.Status, http.StatusText(f.Status))
+}
+
+// route handles a request like Moco would
+func (s *Server) route(w http.ResponseWriter, r *http.Request, path string) {
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "session" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, types.Session{ID: DemoUserID, UUID: "demo-user"})
+	case path == "projects/assigned" && r.Method == http.MethodGet:
+		writePage(w, r, s.perPage, s.projects)
+	case path == "activities" && r.Method == http.MethodGet:
+		s.listActivities(w, r)
+	case path == "activities" && r.Method == http.MethodPost:
+		s.createActivity(w, r)
+	case len(parts) == 2 && parts[0] == "activities":
+		s.activity(w, r, parts[1], "")
+	case len(parts) == 3 && parts[0] == "activities":
+		s.activity(w, r, parts[1], parts[2])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) listActivities(w http.ResponseWriter, r *http.Request) {
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+
+	var entries []types.TimeEntry
+	for _, entry := range s.activities {
+		if (from == "" || entry.Date >= from) && (to == "" || entry.Date <= to) {
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		return entries[i].ID < entries[j].ID
+	})
+
+	writePage(w, r, s.perPage, entries)
+}
+
+func (s *Server) createActivity(w http.ResponseWriter, r *http.Request) {
+	var entry types.TimeEntry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if msg := validate(entry); msg != "" {
+		writeError(w, http.StatusUnprocessableEntity, msg)
+		return
+	}
+	if !resolve(&entry, s.projects) {
+		writeError(w, http.StatusUnprocessableEntity, "project or task not assigned")
+		return
+	}
+
+	entry.ID = s.nextID
+	s.nextID++
+	created := s.now().UTC().Truncate(time.Second)
+	entry.CreatedAt = &created
+	s.activities[entry.ID] = &entry
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (s *Server) activity(w http.ResponseWriter, r *http.Request, rawID, action string) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	entry, ok := s.activities[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "activity not found")
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, entry)
+	case action == "" && r.Method == http.MethodPut:
+		s.updateActivity(w, r, entry)
+	case action == "" && r.Method == http.MethodDelete:
+		delete(s.activities, id)
+		w.WriteHeader(http.StatusNoContent)
+	case action == "start_timer" && r.Method == http.MethodPatch:
+		if entry.Date != s.now().Format("2006-01-02") {
+			writeError(w, http.StatusUnprocessableEntity, "timers can only be started on today's activities")
+			return
+		}
+		for _, other := range s.activities {
+			s.stopTimer(other)
+		}
+		started := s.now()
+		entry.TimerStartedAt = &started
+		writeJSON(w, http.StatusOK, entry)
+	case action == "stop_timer" && r.Method == http.MethodPatch:
+		s.stopTimer(entry)
+		writeJSON(w, http.StatusOK, entry)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) updateActivity(w http.ResponseWriter, r *http.Request, entry *types.TimeEntry) {
+	// Decode onto a copy so unspecified fields keep their values
+	updated := *entry
+	updated.Billable = nil
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if updated.Billable == nil {
+		updated.Billable = entry.Billable
+	}
+	if msg := validate(updated); msg != "" {
+		writeError(w, http.StatusUnprocessableEntity, msg)
+		return
+	}
+	if !resolve(&updated, s.projects) {
+		writeError(w, http.StatusUnprocessableEntity, "project or task not assigned")
+		return
+	}
+
+	*entry = updated
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// stopTimer adds the elapsed time of a running timer to the hours
+func (s *Server) stopTimer(entry *types.TimeEntry) {
+	if entry.TimerStartedAt == nil {
+		return
+	}
+	elapsed := s.now().Sub(*entry.TimerStartedAt).Hours()
+	entry.Hours = float64(int(((entry.Hours+elapsed)*100)+0.5)) / 100
+	entry.TimerStartedAt = nil
+}
+
+func validate(entry types.TimeEntry) string {
+	if _, err := time.Parse("2006-01-02", entry.Date); err != nil {
+		return "date is invalid"
+	}
+	if entry.Hours < 0 {
+		return "hours must not be negative"
+	}
+	return ""
+}
+
+// writePage writes one page of items with Moco's pagination headers
+func writePage[T any](w http.ResponseWriter, r *http.Request, defaultPerPage int, items []T) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+
+	start := (page - 1) * perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + perPage
+	if end > len(items) {
+		end = len(items)
+	}
+
+	w.Header().Set("X-Page", strconv.Itoa(page))
+	w.Header().Set("X-Per-Page", strconv.Itoa(perPage))
+	w.Header().Set("X-Total", strconv.Itoa(len(items)))
+	if end < len(items) {
+		next := *r.URL
+		query := next.Query()
+		query.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, r.Host, next.RequestURI()))
+	}
+
+	pageItems := items[start:end]
+	if pageItems == nil {
+		pageItems = []T{}
+	}
+	writeJSON(w, http.StatusOK, pageItems)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,57 @@
+package mocotest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func request(t *testing.T, s *Server, method, path, body string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequest(method, s.URL()+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Token "+s.APIKey())
+	resp, err := http.DefaultClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestFault(t *testing.T) {
+	s := NewServer(WithActivities(nil))
+	defer s.Close()
+	s.Fail(Fault{Method: http.MethodGet, Path: "activities", Status: http.StatusTooManyRequests, RetryAfter: "2", Count: 2})
+
+	for i := 0; i < 2; i++ {
+		resp, err := request(t, s, http.MethodGet, "activities", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "2" {
+			t.Errorf("request %d: status %d, Retry-After %q", i+1, resp.StatusCode, resp.Header.Get("Retry-After"))
+		}
+	}
+	if resp, err := request(t, s, http.MethodGet, "activities", ""); err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("fault not used up: %v", err)
+	}
+	if got := len(s.Requests()); got != 3 {
+		t.Errorf("%d requests recorded, want 3", got)
+	}
+}
+
+func TestFaultAppliedThenDropped(t *testing.T) {
+	s := NewServer(WithActivities(nil))
+	defer s.Close()
+	s.Fail(Fault{Method: http.MethodPost, Path: "activities", Apply: true})
+
+	body := `{"date":"2026-10-15","hours":1,"project_id":1000,"task_id":2000,"description":"x"}`
+	if _, err := request(t, s, http.MethodPost, "activities", body); err == nil {
+		t.Fatal("dropped request got an answer")
+	}
+	if n := len(s.Activities()); n != 1 {
+		t.Errorf("%d activities stored, want the dropped create to be applied", n)
+	}
+}
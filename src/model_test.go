@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denwerk/moco/src/api"
+	"github.com/denwerk/moco/src/mocotest"
+	"github.com/denwerk/moco/src/types"
+)
+
+func TestSubmitEntryReloadsEntries(t *testing.T) {
+	isolate(t)
+	srv := mocotest.NewServer()
+	defer srv.Close()
+	m := newTestModel(t, srv)
+
+	cmd := typeKeys(m, "down", "1:30", "down", "Pairing", "enter")
+	msg, next := runCmd(t, m, cmd)
+	if submitted, ok := msg.(entrySubmittedMsg); !ok || submitted.err != nil {
+		t.Fatalf("submit sent %#v", msg)
+	}
+	if m.succesMsg == "" || m.errorMsg != "" {
+		t.Errorf("messages = %q, %q, want a success message", m.succesMsg, m.errorMsg)
+	}
+	if _, hours, desc := m.form.GetValues(); hours != "" || desc != "" {
+		t.Error("form wasn't cleared")
+	}
+
+	msg, _ = runCmd(t, m, next)
+	if _, ok := msg.(timeEntriesLoadedMsg); !ok {
+		t.Fatalf("after submit got %T, want the entries to reload", msg)
+	}
+	today := time.Now().Format("2006-01-02")
+	found := false
+	for _, e := range m.timeEntries {
+		if e.Date == today && e.Description == "Pairing" && e.Hours == 1.5 && e.Task.ID == 2000 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("new entry missing from %d loaded entries", len(m.timeEntries))
+	}
+}
+
+func TestEditEntryClearsTagAndTicket(t *testing.T) {
+	isolate(t)
+	stored := types.TimeEntry{ID: 1, Date: time.Now().Format("2006-01-02"), Hours: 1, Description: "Fix",
+		Tag: "bug", RemoteService: "jira", RemoteID: "ACME-42", RemoteURL: "https://jira.example.com/browse/ACME-42",
+		Project: types.Project{ID: 1000, Name: "ACME Website"}, Task: types.Task{ID: 2000, Name: "Development"}}
+	srv := mocotest.NewServer(mocotest.WithActivities([]types.TimeEntry{stored}))
+	defer srv.Close()
+	m := newTestModel(t, srv)
+
+	m.selectedEntry = &stored
+	m.handleEditTimeEntry()
+	keys := []string{"down", "down", "down"}
+	for range stored.RemoteURL {
+		keys = append(keys, "backspace")
+	}
+	keys = append(keys, "down", "down", "backspace", "backspace", "backspace", "enter")
+
+	msg, next := runCmd(t, m, typeKeys(m, keys...))
+	if updated, ok := msg.(entryUpdatedMsg); !ok || updated.err != nil {
+		t.Fatalf("submit sent %#v", msg)
+	}
+	msg, _ = runCmd(t, m, next)
+	if _, ok := msg.(timeEntriesLoadedMsg); !ok {
+		t.Fatalf("after update got %T, want the entries to reload", msg)
+	}
+	got := srv.Activities()[0]
+	if got.Description != "Fix" || got.Tag != "" || got.RemoteID != "" || got.RemoteURL != "" || got.RemoteService != "" {
+		t.Errorf("stored %q with tag %q and ticket %q %q %q, want tag and ticket cleared",
+			got.Description, got.Tag, got.RemoteService, got.RemoteID, got.RemoteURL)
+	}
+}
+
+func TestSubmitInvalidEntrySendsNothing(t *testing.T) {
+	isolate(t)
+	srv := mocotest.NewServer()
+	defer srv.Close()
+	m := newTestModel(t, srv)
+
+	if cmd := typeKeys(m, "down", "abc", "down", "Pairing", "enter"); cmd != nil {
+		t.Error("invalid hours were submitted")
+	}
+	if m.errorMsg == "" {
+		t.Error("no error shown")
+	}
+	if requests := srv.Requests(); len(requests) != 0 {
+		t.Errorf("requests sent: %v", requests)
+	}
+}
+
+func TestWriteResultsOfPreviousProfileAreDropped(t *testing.T) {
+	isolate(t)
+	srv := mocotest.NewServer()
+	defer srv.Close()
+	m := newTestModel(t, srv)
+	previous := newTestModel(t, srv).client
+
+	// A create that was still running when the profile was switched
+	m.Update(entrySubmittedMsg{client: previous, entry: types.TimeEntry{Description: "Old"}, err: &api.Error{StatusCode: 503}})
+	if pending, _ := m.queue.Counts(); pending != 0 {
+		t.Errorf("%d writes queued for the new profile", pending)
+	}
+	if !strings.Contains(m.errorMsg, "previous profile") {
+		t.Errorf("error = %q, want it to name the previous profile", m.errorMsg)
+	}
+
+	m.errorMsg = ""
+	if _, cmd := m.Update(entryDeletedMsg{client: previous, id: 1}); cmd != nil || m.succesMsg != "" {
+		t.Errorf("delete of the previous profile reported as %q", m.succesMsg)
+	}
+}
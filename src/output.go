@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/denwerk/moco/src/export"
+	"github.com/denwerk/moco/src/types"
+)
+
+// outputFormats lists the formats accepted by writeEntries
+var outputFormats = []string{"table", "json", "ndjson", "csv"}
+
+// writeEntries writes entries to w in the given format
+func writeEntries(w io.Writer, format string, entries []types.TimeEntry) error {
+	switch format {
+	case "table", "":
+		return writeEntriesTable(w, entries)
+	case "json", "ndjson", "csv":
+		return export.Write(w, format, entries, export.Options{Dialect: export.DefaultDialect})
+	}
+	return usageErrorf("unknown format %q, use one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+func writeEntriesTable(w io.Writer, entries []types.TimeEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tID\tHOURS\tPROJECT / TASK\tDESCRIPTION\tBILLABLE\tTICKET")
+
+	total := 0.0
+	for _, e := range entries {
+		r := export.NewRecord(e)
+		billable := "no"
+		if r.Billable {
+			billable = "yes"
+		}
+		hours := fmt.Sprintf("%.2f", r.Hours)
+		if r.Running {
+			hours += " ⏱"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s / %s\t%s\t%s\t%s\n",
+			r.Date, r.ID, hours, r.Project, r.Task, r.Description, billable, r.RemoteID)
+		total += r.Hours
+	}
+
+	fmt.Fprintf(tw, "\t\t%.2f\tTotal (%d entries)\t\t\t\n", total, len(entries))
+	return tw.Flush()
+}
+
+// sortEntries orders entries by date, then by ID
+func sortEntries(entries []types.TimeEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		return entries[i].ID < entries[j].ID
+	})
+}
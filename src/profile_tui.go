@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// nextProfile returns the profile after current, wrapping around
+func nextProfile(profiles []string, current string) string {
+	for i, name := range profiles {
+		if name == current {
+			return profiles[(i+1)%len(profiles)]
+		}
+	}
+	return profiles[0]
+}
+
+// switchProfile moves on to the next profile of the config file. The
+// account changes with it, so the model starts over like at startup, with
+// the entries, projects, queue and last task of the new profile.
+func (m *Model) switchProfile() tea.Cmd {
+	if len(m.cfg.Profiles) < 2 {
+		m.setMessage("The config file defines no other profile", true)
+		return nil
+	}
+	if m.replaying {
+		m.setMessage("Queued writes are being replayed, switch profiles when they are done", true)
+		return nil
+	}
+
+	name := nextProfile(m.cfg.Profiles, m.cfg.Profile)
+	cfg, err := loadConfig(ConfigFlags{File: m.cfg.File, Profile: name})
+	if err != nil {
+		m.setMessage(fmt.Sprintf("Error loading profile %s: %v", name, err), true)
+		return nil
+	}
+	client, err := newClient(cfg, m.clientOptions...)
+	if err != nil {
+		m.setMessage(fmt.Sprintf("Error loading profile %s: %v", name, err), true)
+		return nil
+	}
+	stateProfile = cfg.Profile
+
+	cache, err := LoadProjectCache(client.BaseURL())
+	if err != nil {
+		log.Printf("Error loading project cache: %v", err)
+	}
+
+	fresh := newModel(cfg, client, cache, m.keys)
+	fresh.clientOptions = m.clientOptions
+	fresh.retryEvents = m.retryEvents
+	fresh.ticker = m.ticker
+	fresh.focusedPane = m.focusedPane
+	fresh.handleWindowSizeMsg(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+	*m = *fresh
+
+	m.setMessage(fmt.Sprintf("Switched to profile %s", name), false)
+	return tea.Batch(m.loadTimeEntries(), m.refreshProjects())
+}
+
+// dropStaleWrite handles the result of a write sent for the previous
+// profile. It concerns that account, so it is neither shown as this one's
+// nor queued here, but a failure is still reported.
+func (m *Model) dropStaleWrite(err error) {
+	if err != nil {
+		m.setMessage(fmt.Sprintf("A write for the previous profile failed: %s", describeError(err)), true)
+	}
+}
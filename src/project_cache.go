@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/denwerk/moco/src/api"
+	"github.com/denwerk/moco/src/types"
+)
+
+// ProjectCache is the last projects/assigned response stored on disk
+type ProjectCache struct {
+	Account    string          `json:"account"` // API root the projects belong to
+	FetchedAt  time.Time       `json:"fetched_at"`
+	Validators api.Validators  `json:"validators"`
+	Info       api.ListInfo    `json:"info"`
+	Projects   []types.Project `json:"projects"`
+}
+
+// Age returns how long ago the cached projects were confirmed current
+func (c *ProjectCache) Age(now time.Time) time.Duration {
+	return now.Sub(c.FetchedAt)
+}
+
+func getProjectCacheFile() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "projects.json"), nil
+}
+
+// LoadProjectCache returns the cached projects for the account, or nil if
+// there are none. An empty account accepts the cache of any account.
+func LoadProjectCache(account string) (*ProjectCache, error) {
+	cacheFile, err := getProjectCacheFile()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(cacheFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading project cache: %v", err)
+	}
+
+	var cache ProjectCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("error unmarshaling project cache: %v", err)
+	}
+
+	// Don't show another account's projects after switching domains
+	if account != "" && cache.Account != account {
+		return nil, nil
+	}
+
+	return &cache, nil
+}
+
+func SaveProjectCache(cache *ProjectCache) error {
+	cacheFile, err := getProjectCacheFile()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("error marshaling project cache: %v", err)
+	}
+
+	tmpFile := cacheFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		return fmt.Errorf("error writing project cache: %v", err)
+	}
+	if err := os.Rename(tmpFile, cacheFile); err != nil {
+		return fmt.Errorf("error replacing project cache: %v", err)
+	}
+
+	return nil
+}
+
+// formatAge renders a cache age like "just now" or "3h ago"
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
+}
+
+// loadProjects fetches the assigned projects and refreshes the cache. If
+// the API can't be reached it falls back to the cached projects.
+func loadProjects(ctx context.Context, client *api.Client) ([]types.Project, error) {
+	cache, err := LoadProjectCache(client.BaseURL())
+	if err != nil {
+		log.Printf("Error loading project cache: %v", err)
+	}
+
+	var validators api.Validators
+	if cache != nil {
+		validators = cache.Validators
+	}
+
+	projects, info, fresh, err := client.AssignedProjectsIfChanged(ctx, validators)
+	switch {
+	case errors.Is(err, api.ErrNotModified) && cache != nil:
+		cache.FetchedAt = time.Now()
+	case err != nil && cache != nil && shouldQueue(err):
+		return cache.Projects, nil
+	case err != nil:
+		return nil, err
+	default:
+		cache = &ProjectCache{
+			Account:    client.BaseURL(),
+			FetchedAt:  time.Now(),
+			Validators: fresh,
+			Info:       info,
+			Projects:   projects,
+		}
+	}
+
+	if err := SaveProjectCache(cache); err != nil {
+		log.Printf("Error saving project cache: %v", err)
+	}
+	return cache.Projects, nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/denwerk/moco/src/api"
+	"github.com/denwerk/moco/src/types"
+)
+
+// Kinds of queued writes
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// States of queued writes
+const (
+	OpPending  = "pending"  // Waiting to be replayed
+	OpRejected = "rejected" // The server refused it, needs fixing by the user
+	OpConflict = "conflict" // The entry it refers to no longer exists
+)
+
+// QueuedOp is a write that could not be sent and waits for replay
+type QueuedOp struct {
+	ID       string          `json:"id"`
+	Kind     string          `json:"kind"`
+	EntryID  int             `json:"entry_id,omitempty"`
+	Entry    types.TimeEntry `json:"entry"`
+	QueuedAt time.Time       `json:"queued_at"`
+	Status   string          `json:"status"`
+	Error    string          `json:"error,omitempty"`
+	Attempts int             `json:"attempts"`
+	// Uncertain is set when a create may have reached the server before
+	// failing. SentAt is when it was first sent; replay looks for entries
+	// created since then before sending it again.
+	Uncertain bool      `json:"uncertain,omitempty"`
+	SentAt    time.Time `json:"sent_at,omitempty"`
+}
+
+// queuedCreate returns the op for a create that was sent at sentAt and
+// failed with err
+func queuedCreate(entry types.TimeEntry, sentAt time.Time, err error) QueuedOp {
+	op := QueuedOp{Kind: OpCreate, Entry: entry}
+	if api.MayHaveBeenApplied(err) {
+		op.Uncertain = true
+		op.SentAt = sentAt
+	}
+	return op
+}
+
+// Summary describes the op in one line
+func (op QueuedOp) Summary() string {
+	switch op.Kind {
+	case OpDelete:
+		return fmt.Sprintf("delete #%d", op.EntryID)
+	case OpUpdate:
+		return fmt.Sprintf("update #%d: %s %.2fh %s", op.EntryID, op.Entry.Date, op.Entry.Hours, op.Entry.Description)
+	default:
+		return fmt.Sprintf("create: %s %.2fh %s", op.Entry.Date, op.Entry.Hours, op.Entry.Description)
+	}
+}
+
+// WriteQueue is the durable list of writes waiting for connectivity
+type WriteQueue struct {
+	Ops []QueuedOp `json:"ops"`
+}
+
+func getQueueFile() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "queue.json"), nil
+}
+
+func LoadWriteQueue() (*WriteQueue, error) {
+	queueFile, err := getQueueFile()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(queueFile)
+	if os.IsNotExist(err) {
+		return &WriteQueue{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading queue file: %v", err)
+	}
+
+	var queue WriteQueue
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &queue); err != nil {
+			return nil, fmt.Errorf("error unmarshaling queue: %v", err)
+		}
+	}
+
+	return &queue, nil
+}
+
+// Save writes the queue atomically so a crash never leaves a torn file
+func (q *WriteQueue) Save() error {
+	queueFile, err := getQueueFile()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling queue: %v", err)
+	}
+
+	tmpFile := queueFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		return fmt.Errorf("error writing queue file: %v", err)
+	}
+	if err := os.Rename(tmpFile, queueFile); err != nil {
+		return fmt.Errorf("error replacing queue file: %v", err)
+	}
+
+	return nil
+}
+
+// Enqueue appends a write and persists the queue
+func (q *WriteQueue) Enqueue(op QueuedOp) error {
+	if op.ID == "" {
+		op.ID = fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	if op.QueuedAt.IsZero() {
+		op.QueuedAt = time.Now()
+	}
+	op.Status = OpPending
+	q.Ops = append(q.Ops, op)
+	return q.Save()
+}
+
+// Remove drops the op with the given ID and persists the queue
+func (q *WriteQueue) Remove(id string) error {
+	for i, op := range q.Ops {
+		if op.ID == id {
+			q.Ops = append(q.Ops[:i], q.Ops[i+1:]...)
+			return q.Save()
+		}
+	}
+	return nil
+}
+
+// Retry marks an op as pending again. A conflicting update is turned into
+// a create, since the entry it targeted is gone.
+func (q *WriteQueue) Retry(id string) error {
+	for i := range q.Ops {
+		if q.Ops[i].ID != id {
+			continue
+		}
+		if q.Ops[i].Status == OpConflict && q.Ops[i].Kind == OpUpdate {
+			q.Ops[i].Kind = OpCreate
+			q.Ops[i].EntryID = 0
+		}
+		q.Ops[i].Status = OpPending
+		q.Ops[i].Error = ""
+		return q.Save()
+	}
+	return nil
+}
+
+// Counts returns the number of pending and of rejected or conflicting ops
+func (q *WriteQueue) Counts() (pending, failed int) {
+	for _, op := range q.Ops {
+		if op.Status == OpPending {
+			pending++
+		} else {
+			failed++
+		}
+	}
+	return pending, failed
+}
+
+// Pending returns copies of the ops waiting for replay, in queue order
+func (q *WriteQueue) Pending() []QueuedOp {
+	var ops []QueuedOp
+	for _, op := range q.Ops {
+		if op.Status == OpPending {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// ApplyReplay records the outcome of a replay. Ops that were added while
+// the replay ran are left alone.
+func (q *WriteQueue) ApplyReplay(results []QueuedOp) error {
+	byID := make(map[string]QueuedOp)
+	for _, op := range results {
+		byID[op.ID] = op
+	}
+
+	var ops []QueuedOp
+	for _, op := range q.Ops {
+		result, ok := byID[op.ID]
+		if !ok {
+			ops = append(ops, op)
+			continue
+		}
+		if result.Status != "" {
+			ops = append(ops, result)
+		}
+	}
+	q.Ops = ops
+	return q.Save()
+}
+
+// replayOps sends pending ops in order. Each returned op carries its new
+// status, or an empty status if it was applied and can be dropped. Replay
+// stops at the first op that fails for lack of connectivity so that later
+// writes never overtake earlier ones.
+func replayOps(ctx context.Context, client *api.Client, ops []QueuedOp) []QueuedOp {
+	var results []QueuedOp
+	for _, op := range ops {
+		op.Attempts++
+		sentAt := time.Now()
+		err := replayOp(ctx, client, op)
+		switch {
+		case err == nil:
+			op.Status = ""
+			op.Error = ""
+		case shouldQueue(err):
+			op.Error = describeError(err)
+			if op.Kind == OpCreate && !op.Uncertain && api.MayHaveBeenApplied(err) {
+				op.Uncertain = true
+				op.SentAt = sentAt
+			}
+			results = append(results, op)
+			return results
+		case isNotFound(err) && op.Kind == OpDelete:
+			// Already gone, nothing left to do
+			op.Status = ""
+		case isNotFound(err):
+			op.Status = OpConflict
+			op.Error = "the entry was deleted on the server"
+		default:
+			op.Status = OpRejected
+			op.Error = describeError(err)
+		}
+		results = append(results, op)
+	}
+	return results
+}
+
+func replayOp(ctx context.Context, client *api.Client, op QueuedOp) error {
+	switch op.Kind {
+	case OpCreate:
+		if op.Uncertain {
+			// An earlier attempt may have been stored after all. Entries
+			// like it from before then were booked on purpose.
+			sentAt := op.SentAt
+			if sentAt.IsZero() {
+				// Queued before SentAt was recorded; the create was sent
+				// within one request timeout before it was queued
+				sentAt = op.QueuedAt.Add(-requestTimeout)
+			}
+			created, err := client.FindCreatedActivity(ctx, op.Entry, sentAt)
+			if err != nil {
+				return err
+			}
+			if created != nil {
+				return nil
+			}
+		}
+		_, err := client.CreateActivity(ctx, op.Entry)
+		return err
+	case OpUpdate:
+		_, err := client.UpdateActivity(ctx, op.EntryID, op.Entry)
+		return err
+	case OpDelete:
+		return client.DeleteActivity(ctx, op.EntryID)
+	}
+	return fmt.Errorf("unknown queued operation %q", op.Kind)
+}
+
+// shouldQueue reports whether a failed write should be kept for later
+// because the server could not be reached or was temporarily unavailable
+func shouldQueue(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var apiErr *api.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.IsTemporary()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func isNotFound(err error) bool {
+	var apiErr *api.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/denwerk/moco/src/api"
+	"github.com/denwerk/moco/src/mocotest"
+	"github.com/denwerk/moco/src/types"
+)
+
+var queuedEntry = types.TimeEntry{Date: "2024-03-04", Hours: 2, ProjectID: 1000, TaskID: 2000, Description: "Queued"}
+
+// newReplayClient returns a client for srv that retries without waiting
+func newReplayClient(t *testing.T, srv *mocotest.Server) *api.Client {
+	t.Helper()
+	cfg := &Config{MocoDomain: "test", MocoAPIKey: srv.APIKey(), MocoBaseURL: srv.URL()}
+	client, err := newClient(cfg, api.WithLogger(nil),
+		api.WithRetryPolicy(api.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Second}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestQueuedCreate(t *testing.T) {
+	sentAt := time.Now()
+	tests := []struct {
+		name      string
+		err       error
+		uncertain bool
+	}{
+		{"server error", &api.Error{StatusCode: http.StatusBadGateway}, true},
+		{"rate limited", &api.Error{StatusCode: http.StatusTooManyRequests}, false},
+		{"timeout", context.DeadlineExceeded, true},
+	}
+	for _, tt := range tests {
+		op := queuedCreate(queuedEntry, sentAt, tt.err)
+		if op.Kind != OpCreate || op.Uncertain != tt.uncertain {
+			t.Errorf("%s: got %+v, want uncertain %v", tt.name, op, tt.uncertain)
+		}
+		if op.Uncertain && !op.SentAt.Equal(sentAt) {
+			t.Errorf("%s: SentAt = %v, want %v", tt.name, op.SentAt, sentAt)
+		}
+	}
+}
+
+func TestReplayCreateFindsEarlierAttempt(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	client := newReplayClient(t, srv)
+
+	// The create went through, but its answer was lost
+	sentAt := time.Now()
+	if _, err := client.CreateActivity(context.Background(), queuedEntry); err != nil {
+		t.Fatal(err)
+	}
+	op := QueuedOp{ID: "1", Kind: OpCreate, Entry: queuedEntry, Uncertain: true, SentAt: sentAt}
+	results := replayOps(context.Background(), client, []QueuedOp{op})
+	if len(results) != 1 || results[0].Status != "" {
+		t.Fatalf("results = %+v, want the op applied", results)
+	}
+	if n := len(srv.Activities()); n != 1 {
+		t.Errorf("%d activities stored, want 1", n)
+	}
+}
+
+func TestReplayCreateKeepsIntentionalDuplicate(t *testing.T) {
+	earlier := time.Now().Add(-time.Hour)
+	existing := queuedEntry
+	existing.ID = 1
+	existing.CreatedAt = &earlier
+	srv := mocotest.NewServer(mocotest.WithActivities([]types.TimeEntry{existing}))
+	defer srv.Close()
+	client := newReplayClient(t, srv)
+
+	op := QueuedOp{ID: "1", Kind: OpCreate, Entry: queuedEntry, Uncertain: true, SentAt: time.Now()}
+	replayOps(context.Background(), client, []QueuedOp{op})
+	if n := len(srv.Activities()); n != 2 {
+		t.Errorf("%d activities stored, want 2", n)
+	}
+}
+
+func TestReplayCertainCreateSkipsLookup(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	client := newReplayClient(t, srv)
+
+	op := QueuedOp{ID: "1", Kind: OpCreate, Entry: queuedEntry}
+	replayOps(context.Background(), client, []QueuedOp{op})
+	if got := srv.Requests(); !reflect.DeepEqual(got, []string{"POST activities"}) {
+		t.Errorf("requests = %q, want only the POST", got)
+	}
+}
+
+func TestReplayOpsStopsWhileOffline(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	srv.Fail(mocotest.Fault{Method: http.MethodPost, Path: "activities", Status: http.StatusServiceUnavailable, Count: 2})
+	client := newReplayClient(t, srv)
+
+	ops := []QueuedOp{
+		{ID: "1", Kind: OpCreate, Entry: queuedEntry, Status: OpPending},
+		{ID: "2", Kind: OpDelete, EntryID: 1, Status: OpPending},
+	}
+	results := replayOps(context.Background(), client, ops)
+	if len(results) != 1 {
+		t.Fatalf("results = %+v, want only the first op", results)
+	}
+	// The server may have stored the create before failing
+	if op := results[0]; op.Status != OpPending || op.Attempts != 1 || !op.Uncertain || op.SentAt.IsZero() {
+		t.Errorf("result = %+v, want pending and uncertain", op)
+	}
+}
+
+func TestReplayOpsMissingEntries(t *testing.T) {
+	srv := mocotest.NewServer(mocotest.WithActivities(nil))
+	defer srv.Close()
+	client := newReplayClient(t, srv)
+
+	ops := []QueuedOp{
+		{ID: "1", Kind: OpUpdate, EntryID: 42, Entry: queuedEntry, Status: OpPending},
+		{ID: "2", Kind: OpDelete, EntryID: 42, Status: OpPending},
+	}
+	results := replayOps(context.Background(), client, ops)
+	if len(results) != 2 || results[0].Status != OpConflict || results[1].Status != "" {
+		t.Errorf("results = %+v, want a conflict and a dropped delete", results)
+	}
+}
+
+func TestWriteQueue(t *testing.T) {
+	isolate(t)
+
+	q, err := LoadWriteQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []QueuedOp{
+		{ID: "1", Kind: OpCreate, Entry: queuedEntry},
+		{ID: "2", Kind: OpUpdate, EntryID: 7, Entry: queuedEntry},
+	} {
+		if err := q.Enqueue(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Replay applied the first and found the target of the second gone;
+	// a third was queued meanwhile
+	results := []QueuedOp{q.Ops[0], q.Ops[1]}
+	results[0].Status = ""
+	results[1].Status = OpConflict
+	if err := q.Enqueue(QueuedOp{ID: "3", Kind: OpDelete, EntryID: 8}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.ApplyReplay(results); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadWriteQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending, failed := loaded.Counts(); pending != 1 || failed != 1 {
+		t.Fatalf("counts = %d pending, %d failed, want 1 and 1: %+v", pending, failed, loaded.Ops)
+	}
+
+	// Retrying the conflict books the entry anew
+	if err := loaded.Retry("2"); err != nil {
+		t.Fatal(err)
+	}
+	if op := loaded.Ops[0]; op.Kind != OpCreate || op.EntryID != 0 || op.Status != OpPending {
+		t.Errorf("retried op = %+v, want a pending create", op)
+	}
+}
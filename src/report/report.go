@@ -0,0 +1,147 @@
+// Package report aggregates time entries into totals per day, project,
+// customer and task.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// Group sums up the entries that share a day, project, customer or task
+type Group struct {
+	Key      string  `json:"key"`  // The date for days, otherwise the ID
+	Name     string  `json:"name"` // Display name, e.g. "Project / Task" for tasks
+	Hours    float64 `json:"hours"`
+	Billable float64 `json:"billable_hours"`
+	Entries  []int   `json:"-"` // Indexes into the aggregated entries, in their order
+}
+
+// Summary aggregates the entries of a date range
+type Summary struct {
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Hours     float64 `json:"hours"`
+	Billable  float64 `json:"billable_hours"`
+	Count     int     `json:"entries"`
+	Days      []Group `json:"days"`
+	Customers []Group `json:"customers"`
+	Projects  []Group `json:"projects"`
+	Tasks     []Group `json:"tasks"`
+}
+
+// Summarize aggregates the entries dated from through to, both inclusive
+// and in YYYY-MM-DD form. Entries outside the range are ignored.
+func Summarize(entries []types.TimeEntry, from, to string) Summary {
+	s := Summary{From: from, To: to}
+
+	inRange := func(e types.TimeEntry) bool { return e.Date >= from && e.Date <= to }
+	for _, e := range entries {
+		if !inRange(e) {
+			continue
+		}
+		s.Count++
+		s.Hours += e.Hours
+		if e.IsBillable() {
+			s.Billable += e.Hours
+		}
+	}
+
+	s.Days = aggregate(entries, inRange, dayKey)
+	s.Customers = byHours(aggregate(entries, inRange, customerKey))
+	s.Projects = byHours(aggregate(entries, inRange, projectKey))
+	s.Tasks = byHours(aggregate(entries, inRange, taskKey))
+	return s
+}
+
+// ByDay groups entries by date, earliest first
+func ByDay(entries []types.TimeEntry) []Group {
+	return aggregate(entries, nil, dayKey)
+}
+
+func dayKey(e types.TimeEntry) (string, string) {
+	return e.Date, e.Date
+}
+
+func customerKey(e types.TimeEntry) (string, string) {
+	return fmt.Sprint(e.Customer.ID), e.Customer.Name
+}
+
+func projectKey(e types.TimeEntry) (string, string) {
+	id := e.ProjectID
+	if id == 0 {
+		id = e.Project.ID
+	}
+	return fmt.Sprint(id), e.Project.Name
+}
+
+func taskKey(e types.TimeEntry) (string, string) {
+	id := e.TaskID
+	if id == 0 {
+		id = e.Task.ID
+	}
+	return fmt.Sprint(id), e.Project.Name + " / " + e.Task.Name
+}
+
+// aggregate groups the entries accepted by include (all if nil) by key.
+// Groups are sorted by key.
+func aggregate(entries []types.TimeEntry, include func(types.TimeEntry) bool, key func(types.TimeEntry) (string, string)) []Group {
+	index := map[string]int{}
+	var groups []Group
+	for i, e := range entries {
+		if include != nil && !include(e) {
+			continue
+		}
+		k, name := key(e)
+		gi, ok := index[k]
+		if !ok {
+			gi = len(groups)
+			index[k] = gi
+			groups = append(groups, Group{Key: k, Name: name})
+		}
+		g := &groups[gi]
+		g.Hours += e.Hours
+		if e.IsBillable() {
+			g.Billable += e.Hours
+		}
+		g.Entries = append(g.Entries, i)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+// byHours sorts groups by hours, most first, then by name
+func byHours(groups []Group) []Group {
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Hours != groups[j].Hours {
+			return groups[i].Hours > groups[j].Hours
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// Target returns the hours expected from from through to given a weekly
+// target, spread evenly over Monday to Friday. Dates are YYYY-MM-DD;
+// invalid dates or an empty range yield 0.
+func Target(weekly float64, from, to string) float64 {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return 0
+	}
+
+	workdays := 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			workdays++
+		}
+	}
+	return weekly / 5 * float64(workdays)
+}
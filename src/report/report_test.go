@@ -0,0 +1,156 @@
+package report
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+func entry(date string, hours float64, customer, project, task int, billable bool) types.TimeEntry {
+	names := map[int]string{1: "Acme", 2: "Globex", 10: "Website", 11: "Shop", 20: "Design", 21: "Support", 22: "Design"}
+	return types.TimeEntry{
+		Date:     date,
+		Hours:    hours,
+		Billable: &billable,
+		Customer: types.Customer{ID: customer, Name: names[customer]},
+		Project:  types.Project{ID: project, Name: names[project]},
+		Task:     types.Task{ID: task, Name: names[task]},
+	}
+}
+
+func testEntries() []types.TimeEntry {
+	return []types.TimeEntry{
+		entry("2026-10-13", 2, 1, 10, 20, true),
+		entry("2026-10-12", 1.5, 1, 10, 21, false),
+		entry("2026-10-12", 3, 2, 11, 22, true),
+		entry("2026-10-14", 0.5, 1, 10, 20, true),
+		entry("2026-10-11", 8, 2, 11, 22, true), // Before the range
+		entry("2026-10-19", 8, 1, 10, 20, true), // After the range
+	}
+}
+
+type total struct {
+	Key      string
+	Name     string
+	Hours    float64
+	Billable float64
+}
+
+func totals(groups []Group) []total {
+	var got []total
+	for _, g := range groups {
+		got = append(got, total{g.Key, g.Name, g.Hours, g.Billable})
+	}
+	return got
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize(testEntries(), "2026-10-12", "2026-10-18")
+
+	if s.From != "2026-10-12" || s.To != "2026-10-18" || s.Count != 4 || s.Hours != 7 || s.Billable != 5.5 {
+		t.Errorf("summary = %s to %s, %d entries, %.2fh, %.2fh billable, want 2026-10-12 to 2026-10-18, 4 entries, 7h, 5.5h billable",
+			s.From, s.To, s.Count, s.Hours, s.Billable)
+	}
+
+	tests := []struct {
+		name   string
+		groups []Group
+		want   []total
+	}{
+		{"days", s.Days, []total{
+			{"2026-10-12", "2026-10-12", 4.5, 3},
+			{"2026-10-13", "2026-10-13", 2, 2},
+			{"2026-10-14", "2026-10-14", 0.5, 0.5},
+		}},
+		{"customers", s.Customers, []total{
+			{"1", "Acme", 4, 2.5},
+			{"2", "Globex", 3, 3},
+		}},
+		{"projects", s.Projects, []total{
+			{"10", "Website", 4, 2.5},
+			{"11", "Shop", 3, 3},
+		}},
+		{"tasks", s.Tasks, []total{
+			{"22", "Shop / Design", 3, 3},
+			{"20", "Website / Design", 2.5, 2.5},
+			{"21", "Website / Support", 1.5, 0},
+		}},
+	}
+	for _, tt := range tests {
+		if got := totals(tt.groups); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeOrdersTiesByName(t *testing.T) {
+	entries := []types.TimeEntry{
+		entry("2026-10-12", 2, 2, 11, 22, true),
+		entry("2026-10-12", 2, 1, 10, 20, true),
+	}
+	s := Summarize(entries, "2026-10-12", "2026-10-12")
+	if got := []string{s.Customers[0].Name, s.Customers[1].Name}; got[0] != "Acme" || got[1] != "Globex" {
+		t.Errorf("customers with equal hours in order %v, want by name", got)
+	}
+}
+
+func TestSummarizePrefersEntryIDs(t *testing.T) {
+	e := entry("2026-10-12", 1, 1, 10, 20, true)
+	e.ProjectID, e.TaskID = 99, 98
+	s := Summarize([]types.TimeEntry{e}, "2026-10-12", "2026-10-12")
+	if s.Projects[0].Key != "99" || s.Tasks[0].Key != "98" {
+		t.Errorf("project key %s, task key %s, want 99 and 98", s.Projects[0].Key, s.Tasks[0].Key)
+	}
+}
+
+func TestSummarizeUnknownBillable(t *testing.T) {
+	e := entry("2026-10-12", 1, 1, 10, 20, false)
+	e.Billable = nil
+	if s := Summarize([]types.TimeEntry{e}, "2026-10-12", "2026-10-12"); s.Billable != 1 {
+		t.Errorf("billable = %.2f, want entries of unknown billability counted as billable", s.Billable)
+	}
+}
+
+func TestByDay(t *testing.T) {
+	entries := testEntries()
+	days := ByDay(entries)
+
+	var keys []string
+	for _, d := range days {
+		keys = append(keys, d.Key)
+	}
+	if want := []string{"2026-10-11", "2026-10-12", "2026-10-13", "2026-10-14", "2026-10-19"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("days = %v, want %v", keys, want)
+	}
+	if got := days[1].Entries; !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("entries of 2026-10-12 = %v, want indexes [1 2]", got)
+	}
+}
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		weekly   float64
+		from, to string
+		want     float64
+	}{
+		{40, "2026-10-12", "2026-10-18", 40},  // Monday to Sunday
+		{40, "2026-10-12", "2026-10-16", 40},  // Monday to Friday
+		{40, "2026-10-17", "2026-10-18", 0},   // Weekend only
+		{40, "2026-10-14", "2026-10-14", 8},   // A Wednesday
+		{30, "2026-10-12", "2026-10-14", 18},  // Part of a week
+		{40, "2026-10-01", "2026-10-31", 176}, // 22 workdays in October 2026
+		{40, "2028-02-01", "2028-02-29", 168}, // 21 workdays in February of a leap year
+		{40, "2026-12-28", "2027-01-03", 40},  // Across the year
+		{40, "2026-10-18", "2026-10-12", 0},   // Empty range
+		{40, "2026-10-12", "someday", 0},      // Invalid date
+		{0, "2026-10-12", "2026-10-18", 0},    // No target
+		{38.5, "2026-10-12", "2026-10-18", 38.5},
+	}
+	for _, tt := range tests {
+		if got := Target(tt.weekly, tt.from, tt.to); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Target(%v, %s, %s) = %v, want %v", tt.weekly, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -13,13 +13,34 @@ type LastTask struct {
 	TaskTitle string `json:"task_title"`
 }
 
+// stateProfile is the profile whose local state is used. Each named profile
+// keeps its queue, caches and timer in a directory of its own under
+// profiles/; without a profile, or for one named "default", the state lives
+// in the config directory itself. LoadConfig sets it.
+var stateProfile string
+
 func getConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("error getting home directory: %v", err)
-	}
+	return profileDir(stateProfile)
+}
 
-	configDir := filepath.Join(homeDir, ".moco")
+// profileDir returns the state directory of a profile, see stateProfile,
+// and creates it if needed
+func profileDir(profile string) (string, error) {
+	// MOCO_CONFIG_DIR keeps demo runs and tests away from the real state
+	configDir := os.Getenv("MOCO_CONFIG_DIR")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("error getting home directory: %v", err)
+		}
+		configDir = filepath.Join(homeDir, ".moco")
+	}
+	if profile != "" && profile != "default" {
+		if !profileNamePattern.MatchString(profile) {
+			return "", fmt.Errorf("invalid profile name %q", profile)
+		}
+		configDir = filepath.Join(configDir, "profiles", profile)
+	}
 
 	// Ensure directory exists with correct permissions
 	if err := os.MkdirAll(configDir, 0700); err != nil {
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// taskRef is a task together with the project it belongs to
+type taskRef struct {
+	Project types.Project
+	Task    types.Task
+}
+
+// Label names the task the way it is shown and matched: "Project / Task"
+func (t taskRef) Label() string {
+	return t.Project.Name + " / " + t.Task.Name
+}
+
+// Billable is the default billable flag for new entries on the task
+func (t taskRef) Billable() bool {
+	return t.Project.Billable && t.Task.Billable
+}
+
+// allTasks lists every task of the projects, sorted by label
+func allTasks(projects []types.Project) []taskRef {
+	var tasks []taskRef
+	for _, project := range projects {
+		for _, task := range project.Tasks {
+			tasks = append(tasks, taskRef{Project: project, Task: task})
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return strings.ToLower(tasks[i].Label()) < strings.ToLower(tasks[j].Label())
+	})
+	return tasks
+}
+
+// findTask resolves a task ID, a "Project / Task" label, a task name or
+// words that occur in exactly one label
+func findTask(projects []types.Project, query string) (taskRef, error) {
+	tasks := allTasks(projects)
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return taskRef{}, usageErrorf("a task is required")
+	}
+
+	if id, err := strconv.Atoi(query); err == nil {
+		for _, t := range tasks {
+			if t.Task.ID == id {
+				return t, nil
+			}
+		}
+		return taskRef{}, usageErrorf("no assigned task with ID %d", id)
+	}
+
+	lower := strings.ToLower(query)
+	var exact, byName, partial []taskRef
+	for _, t := range tasks {
+		label := strings.ToLower(t.Label())
+		switch {
+		case label == lower:
+			exact = append(exact, t)
+		case strings.ToLower(t.Task.Name) == lower:
+			byName = append(byName, t)
+		case containsAllWords(label, lower):
+			partial = append(partial, t)
+		}
+	}
+
+	for _, candidates := range [][]taskRef{exact, byName, partial} {
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			return candidates[0], nil
+		default:
+			return taskRef{}, ambiguousTask(query, candidates)
+		}
+	}
+
+	return taskRef{}, usageErrorf("no assigned task matches %q", query)
+}
+
+// containsAllWords reports whether every word of query occurs in s
+func containsAllWords(s, query string) bool {
+	words := strings.Fields(query)
+	for _, word := range words {
+		if !strings.Contains(s, word) {
+			return false
+		}
+	}
+	return len(words) > 0
+}
+
+func ambiguousTask(query string, candidates []taskRef) error {
+	var labels []string
+	for i, t := range candidates {
+		if i == 5 {
+			labels = append(labels, fmt.Sprintf("... and %d more", len(candidates)-5))
+			break
+		}
+		labels = append(labels, fmt.Sprintf("%s (%d)", t.Label(), t.Task.ID))
+	}
+	return usageErrorf("%q matches several tasks: %s", query, strings.Join(labels, ", "))
+}
+
+// findProjectTask resolves a task given separately from its project, as in
+// an imported spreadsheet. Both accept an ID, a name or a unique part of
+// the name. Without a project the task is looked up like findTask does.
+func findProjectTask(projects []types.Project, project, task string) (taskRef, error) {
+	project, task = strings.TrimSpace(project), strings.TrimSpace(task)
+	if project == "" {
+		return findTask(projects, task)
+	}
+
+	p, err := findByName(projects, project, "project",
+		func(p types.Project) (int, string) { return p.ID, p.Name })
+	if err != nil {
+		return taskRef{}, err
+	}
+	if task == "" {
+		return taskRef{}, usageErrorf("a task is required")
+	}
+	t, err := findByName(p.Tasks, task, "task",
+		func(t types.Task) (int, string) { return t.ID, t.Name })
+	if err != nil {
+		return taskRef{}, usageErrorf("%s: %v", p.Name, err)
+	}
+	return taskRef{Project: p, Task: t}, nil
+}
+
+// findByName picks the item whose ID or name equals query, or failing that
+// the only one whose name contains it
+func findByName[T any](items []T, query, kind string, key func(T) (int, string)) (T, error) {
+	var zero T
+	lower := strings.ToLower(query)
+	var partial []T
+	for _, item := range items {
+		id, name := key(item)
+		if strconv.Itoa(id) == query || strings.ToLower(name) == lower {
+			return item, nil
+		}
+		if strings.Contains(strings.ToLower(name), lower) {
+			partial = append(partial, item)
+		}
+	}
+
+	switch len(partial) {
+	case 0:
+		return zero, usageErrorf("no assigned %s matches %q", kind, query)
+	case 1:
+		return partial[0], nil
+	}
+	var names []string
+	for _, item := range partial {
+		_, name := key(item)
+		names = append(names, name)
+	}
+	return zero, usageErrorf("%q matches several %ss: %s", query, kind, strings.Join(names, ", "))
+}
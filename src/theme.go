@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/denwerk/moco/src/ui"
+)
+
+// loadTheme picks the theme of the TUI. MOCO_THEME names one of ui.Themes;
+// "auto", the default, chooses dark or light by the terminal background.
+// The [colors] table of the config file adjusts single colours. NO_COLOR
+// (https://no-color.org) always gets the monochrome theme. It also returns
+// how the theme was chosen, for doctor.
+func loadTheme(cfg *Config) (ui.Theme, string, error) {
+	name := cfg.Theme
+	if name == "" {
+		name = "auto"
+	}
+	if _, ok := ui.Themes[name]; !ok && name != "auto" {
+		return ui.Theme{}, "", settingError("MOCO_THEME", cfg.Sources,
+			fmt.Errorf("unknown theme %q, use auto, %s", name, strings.Join(ui.ThemeNames(), ", ")))
+	}
+	// Checked up front, so mistakes show up whatever the terminal
+	if _, err := ui.Themes["dark"].WithColors(cfg.Colors); err != nil {
+		return ui.Theme{}, "", &ConfigError{fmt.Sprintf("colors in %s: %v", cfg.File, err)}
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return ui.Themes["monochrome"], "NO_COLOR is set", nil
+	}
+	reason := "set by MOCO_THEME"
+	if name == "auto" {
+		name, reason = "light", "detected a light background"
+		if lipgloss.HasDarkBackground() {
+			name, reason = "dark", "detected a dark background"
+		}
+	}
+	theme, _ := ui.Themes[name].WithColors(cfg.Colors)
+	return theme, reason, nil
+}
@@ -0,0 +1,113 @@
+// Package ticket links time entries to issue tracker tickets.
+package ticket
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Rule recognises ticket references of one tracker
+type Rule struct {
+	Service     string         // Moco remote_service, e.g. "jira" or "github"
+	Pattern     *regexp.Regexp // Matches a reference; the first group, if any, is the ID
+	URLTemplate string         // Ticket URL with {id} replaced by the ID
+}
+
+// Ref is a resolved ticket reference
+type Ref struct {
+	Service string
+	ID      string
+	URL     string
+}
+
+// JiraRule matches keys like ABC-123 and links them below baseURL, e.g.
+// https://acme.atlassian.net/browse/
+func JiraRule(baseURL string) Rule {
+	return Rule{
+		Service:     "jira",
+		Pattern:     regexp.MustCompile(`\b([A-Z][A-Z0-9]+-[0-9]+)\b`),
+		URLTemplate: strings.TrimSuffix(baseURL, "/") + "/{id}",
+	}
+}
+
+// GitHubRule matches references like #456 to issues of repo ("owner/name")
+func GitHubRule(repo string) Rule {
+	return Rule{
+		Service:     "github",
+		Pattern:     regexp.MustCompile(`(?:^|\s)#([0-9]+)\b`),
+		URLTemplate: "https://github.com/" + strings.Trim(repo, "/") + "/issues/{id}",
+	}
+}
+
+// ParseRules parses rules written as "service|pattern|url-template",
+// separated by semicolons. The pattern may itself contain "|"; spaces
+// around the parts are ignored.
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first := strings.Index(part, "|")
+		last := strings.LastIndex(part, "|")
+		if first < 0 || first == last {
+			return nil, fmt.Errorf("invalid ticket rule %q: expected service|pattern|url-template", part)
+		}
+
+		pattern, err := regexp.Compile(strings.TrimSpace(part[first+1 : last]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ticket rule %q: %v", part, err)
+		}
+
+		rules = append(rules, Rule{
+			Service:     strings.TrimSpace(part[:first]),
+			Pattern:     pattern,
+			URLTemplate: strings.TrimSpace(part[last+1:]),
+		})
+	}
+	return rules, nil
+}
+
+// Detect finds the first ticket reference in text, trying rules in order
+func Detect(rules []Rule, text string) (Ref, bool) {
+	for _, rule := range rules {
+		if m := rule.Pattern.FindStringSubmatch(text); m != nil {
+			return rule.ref(m), true
+		}
+	}
+	return Ref{}, false
+}
+
+// Resolve interprets an explicitly entered ticket reference. Besides
+// anything the rules recognise, a plain URL is accepted as is.
+func Resolve(rules []Rule, input string) (Ref, error) {
+	input = strings.TrimSpace(input)
+	if ref, ok := Detect(rules, input); ok {
+		return ref, nil
+	}
+
+	if u, err := url.Parse(input); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		return Ref{ID: path.Base(u.Path), URL: input}, nil
+	}
+
+	return Ref{}, fmt.Errorf("unrecognised ticket reference %q", input)
+}
+
+func (r Rule) ref(match []string) Ref {
+	id := match[0]
+	if len(match) > 1 && match[1] != "" {
+		id = match[1]
+	}
+	id = strings.TrimSpace(id)
+
+	ref := Ref{Service: r.Service, ID: id}
+	if r.URLTemplate != "" {
+		ref.URL = strings.ReplaceAll(r.URLTemplate, "{id}", url.PathEscape(id))
+	}
+	return ref
+}
@@ -0,0 +1,84 @@
+package ticket
+
+import "testing"
+
+func testRules(t *testing.T) []Rule {
+	t.Helper()
+	custom, err := ParseRules("linear|\\b(ENG-[0-9]+)\\b|https://linear.app/acme/issue/{id}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(custom, JiraRule("https://acme.atlassian.net/browse/"), GitHubRule("/acme/app/"))
+}
+
+func TestDetect(t *testing.T) {
+	rules := testRules(t)
+	tests := []struct {
+		text string
+		want Ref
+		ok   bool
+	}{
+		{"Fix login ABC-123", Ref{"jira", "ABC-123", "https://acme.atlassian.net/browse/ABC-123"}, true},
+		{"Review #456", Ref{"github", "456", "https://github.com/acme/app/issues/456"}, true},
+		// Custom rules come first, so ENG-7 isn't taken for a Jira key
+		{"ENG-7 and ABC-1", Ref{"linear", "ENG-7", "https://linear.app/acme/issue/ENG-7"}, true},
+		{"Meeting about the roadmap", Ref{}, false},
+		{"abc-123 issue#9", Ref{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Detect(rules, tt.text)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Detect(%q) = %+v, %v, want %+v, %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	rules := testRules(t)
+	got, err := Resolve(rules, " #12 ")
+	if err != nil || got.ID != "12" || got.Service != "github" {
+		t.Errorf("Resolve(#12) = %+v, %v", got, err)
+	}
+
+	url := "https://tracker.example.com/tickets/981"
+	got, err = Resolve(rules, url)
+	if err != nil || got != (Ref{ID: "981", URL: url}) {
+		t.Errorf("Resolve(%q) = %+v, %v", url, got, err)
+	}
+
+	if got, err := Resolve(rules, "next week"); err == nil {
+		t.Errorf("Resolve(next week) = %+v, want an error", got)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(" ; yt | (?:YT|yt)-([0-9]+) | https://yt.example.com/{id} ;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("got %d rules, want 1", len(rules))
+	}
+	// The pattern keeps its "|" but not the spaces around it
+	ref, ok := Detect(rules, "see yt-42")
+	if !ok || ref != (Ref{"yt", "42", "https://yt.example.com/42"}) {
+		t.Errorf("Detect = %+v, %v", ref, ok)
+	}
+
+	for _, spec := range []string{"jira", "jira|ABC-[0-9]+", "jira|([A-Z|https://x/{id}"} {
+		if _, err := ParseRules(spec); err == nil {
+			t.Errorf("ParseRules(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestRefEscapesID(t *testing.T) {
+	rules, err := ParseRules("wiki|page:(\\S+)|https://wiki.example.com/{id}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := Detect(rules, "page:a/b?c")
+	if want := "https://wiki.example.com/a%2Fb%3Fc"; ref.URL != want {
+		t.Errorf("URL = %q, want %q", ref.URL, want)
+	}
+}
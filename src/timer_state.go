@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// TimerState is the running timer as last seen by the TUI or a timer
+// command. It lets 'moco timer status' answer without asking Moco, so the
+// timer can be shown in a shell prompt or tmux status line.
+type TimerState struct {
+	EntryID     int       `json:"entry_id"`
+	ProjectID   int       `json:"project_id"`
+	TaskID      int       `json:"task_id"`
+	Project     string    `json:"project"`
+	Task        string    `json:"task"`
+	Description string    `json:"description"`
+	Hours       float64   `json:"hours"` // Booked on the entry before the timer started
+	StartedAt   time.Time `json:"started_at"`
+}
+
+// newTimerState captures an entry with a running timer
+func newTimerState(entry types.TimeEntry) *TimerState {
+	state := &TimerState{
+		EntryID:     entry.ID,
+		ProjectID:   entry.Project.ID,
+		TaskID:      entry.Task.ID,
+		Project:     entry.Project.Name,
+		Task:        entry.Task.Name,
+		Description: entry.Description,
+		Hours:       entry.Hours,
+	}
+	if entry.TimerStartedAt != nil {
+		state.StartedAt = *entry.TimerStartedAt
+	}
+	return state
+}
+
+// Elapsed is the time on the entry including the running timer
+func (s *TimerState) Elapsed(now time.Time) time.Duration {
+	return time.Duration(s.Hours*float64(time.Hour)) + now.Sub(s.StartedAt)
+}
+
+func getTimerStateFile() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "timer.json"), nil
+}
+
+// LoadTimerState returns the last seen running timer, or nil if there is none
+func LoadTimerState() (*TimerState, error) {
+	stateFile, err := getTimerStateFile()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading timer state: %v", err)
+	}
+
+	var state TimerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("error parsing timer state: %v", err)
+	}
+	return &state, nil
+}
+
+// SaveTimerState records the running timer. A nil state records that no
+// timer is running.
+func SaveTimerState(state *TimerState) error {
+	stateFile, err := getTimerStateFile()
+	if err != nil {
+		return err
+	}
+
+	if state == nil {
+		if err := os.Remove(stateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing timer state: %v", err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error marshaling timer state: %v", err)
+	}
+
+	tmpFile := stateFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		return fmt.Errorf("error writing timer state: %v", err)
+	}
+	if err := os.Rename(tmpFile, stateFile); err != nil {
+		return fmt.Errorf("error replacing timer state: %v", err)
+	}
+	return nil
+}
+
+// syncTimerState updates the state file to the running timer seen in a
+// list of entries, nil if none runs. It only writes when something changed.
+func syncTimerState(running *types.TimeEntry) error {
+	current, err := LoadTimerState()
+	if err != nil {
+		return err
+	}
+
+	if running == nil {
+		if current == nil {
+			return nil
+		}
+		return SaveTimerState(nil)
+	}
+
+	state := newTimerState(*running)
+	if current != nil && current.StartedAt.Equal(state.StartedAt) {
+		// Compare the rest; the times may differ in their location only
+		unchanged := *current
+		unchanged.StartedAt = state.StartedAt
+		if unchanged == *state {
+			return nil
+		}
+	}
+	return SaveTimerState(state)
+}
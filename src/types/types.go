@@ -1,10 +1,16 @@
 package types
 
+import "time"
+
 type Project struct {
-	ID       int      `json:"id"`
-	Name     string   `json:"name"`
-	Customer Customer `json:"customer"`
-	Tasks    []Task   `json:"tasks"`
+	ID         int      `json:"id"`
+	Identifier string   `json:"identifier,omitempty"`
+	Name       string   `json:"name"`
+	Billable   bool     `json:"billable"`
+	Budget     *float64 `json:"budget,omitempty"` // In Currency; nil if the project has none
+	Currency   string   `json:"currency,omitempty"`
+	Customer   Customer `json:"customer"`
+	Tasks      []Task   `json:"tasks"`
 }
 
 type Customer struct {
@@ -13,8 +19,9 @@ type Customer struct {
 }
 
 type Task struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Billable bool   `json:"billable"`
 }
 
 type TimeEntry struct {
@@ -24,5 +31,37 @@ type TimeEntry struct {
 	ProjectID   int     `json:"project_id"`
 	TaskID      int     `json:"task_id"`
 	Description string  `json:"description"`
-	Task        Task    `json:"task"`
+	Billable    *bool   `json:"billable"` // nil leaves the choice to the server
+	Tag         string  `json:"tag"`
+	// Remote fields link the entry to an issue tracker ticket
+	RemoteService string   `json:"remote_service,omitempty"`
+	RemoteID      string   `json:"remote_id,omitempty"`
+	RemoteURL     string   `json:"remote_url,omitempty"`
+	Project       Project  `json:"project"`
+	Task          Task     `json:"task"`
+	Customer      Customer `json:"customer"`
+	// TimerStartedAt is set while a timer is running on the entry
+	TimerStartedAt *time.Time `json:"timer_started_at"`
+	// CreatedAt is when the server stored the entry, by the server's clock
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// IsBillable reports whether the entry is billable, treating unknown as billable
+func (e TimeEntry) IsBillable() bool {
+	return e.Billable == nil || *e.Billable
+}
+
+// Elapsed returns the booked hours plus the time of a running timer
+func (e TimeEntry) Elapsed(now time.Time) time.Duration {
+	elapsed := time.Duration(e.Hours * float64(time.Hour))
+	if e.TimerStartedAt != nil {
+		elapsed += now.Sub(*e.TimerStartedAt)
+	}
+	return elapsed
+}
+
+// Session identifies the user an API key belongs to
+type Session struct {
+	ID   int    `json:"id"`
+	UUID string `json:"uuid"`
 }
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// CopyItem is an entry offered in the copy dialog
+type CopyItem struct {
+	Label       string // "Project / Task"
+	Hours       string
+	Description string
+}
+
+// CopyForm reviews entries before they are copied to a target date or
+// range. Focus moves through the target dates, then hours and description
+// of each entry.
+type CopyForm struct {
+	target textinput.Model
+	until  textinput.Model
+	hours  []textinput.Model
+	descs  []textinput.Model
+	labels []string
+	focus  int
+	err    string
+	hint   string
+}
+
+// NewCopyForm creates the dialog with the target date preset
+func NewCopyForm(items []CopyItem, target string) *CopyForm {
+	f := &CopyForm{
+		target: textinput.New(),
+		until:  textinput.New(),
+	}
+	f.SetKeyHints("enter", "esc", "tab")
+	f.target.SetValue(target)
+	f.target.Placeholder = "YYYY-MM-DD, today or a weekday"
+	f.until.Placeholder = "optional last day of a range"
+
+	for _, item := range items {
+		hours := textinput.New()
+		hours.SetValue(item.Hours)
+		hours.Width = 6
+		desc := textinput.New()
+		desc.SetValue(item.Description)
+		f.hours = append(f.hours, hours)
+		f.descs = append(f.descs, desc)
+		f.labels = append(f.labels, item.Label)
+	}
+	f.focusCurrent()
+	return f
+}
+
+func (f *CopyForm) fieldCount() int {
+	return 2 + 2*len(f.hours)
+}
+
+// input returns the text input with the given focus index
+func (f *CopyForm) input(i int) *textinput.Model {
+	switch {
+	case i == 0:
+		return &f.target
+	case i == 1:
+		return &f.until
+	case i%2 == 0:
+		return &f.hours[(i-2)/2]
+	default:
+		return &f.descs[(i-2)/2]
+	}
+}
+
+func (f *CopyForm) focusCurrent() {
+	for i := 0; i < f.fieldCount(); i++ {
+		f.input(i).Blur()
+	}
+	f.input(f.focus).Focus()
+}
+
+// PrevField moves the focus to the previous field
+func (f *CopyForm) PrevField() {
+	f.focus = (f.focus - 1 + f.fieldCount()) % f.fieldCount()
+	f.focusCurrent()
+}
+
+// NextField moves the focus to the next field
+func (f *CopyForm) NextField() {
+	f.focus = (f.focus + 1) % f.fieldCount()
+	f.focusCurrent()
+}
+
+// Update edits the focused field
+func (f *CopyForm) Update(msg tea.KeyMsg) {
+	input := f.input(f.focus)
+	*input, _ = input.Update(msg)
+}
+
+// SetKeyHints sets the keys named in the help line
+func (f *CopyForm) SetKeyHints(submit, back, next string) {
+	f.hint = fmt.Sprintf("'%s' copy, '%s' cancel, '%s' next field; hours '-' leaves an entry out, ranges skip weekends", submit, back, next)
+}
+
+// Values returns the target dates and the reviewed hours and descriptions
+func (f *CopyForm) Values() (target, until string, hours, descs []string) {
+	for i := range f.hours {
+		hours = append(hours, f.hours[i].Value())
+		descs = append(descs, f.descs[i].Value())
+	}
+	return f.target.Value(), f.until.Value(), hours, descs
+}
+
+// SetError shows why the entries can't be copied
+func (f *CopyForm) SetError(err string) {
+	f.err = err
+}
+
+func (f *CopyForm) View() string {
+	lines := []string{
+		TitleStyle.Render(fmt.Sprintf("Copy %d entries", len(f.hours))),
+		fmt.Sprintf("To:    %s", f.target.View()),
+		fmt.Sprintf("Until: %s", f.until.View()),
+		"",
+	}
+	for i, label := range f.labels {
+		lines = append(lines,
+			HeaderStyle.Render(label),
+			fmt.Sprintf("  Hours: %s", f.hours[i].View()),
+			fmt.Sprintf("  Description: %s", f.descs[i].View()),
+		)
+	}
+	if f.err != "" {
+		lines = append(lines, ErrorStyle.Render("Error: "+f.err))
+	}
+	lines = append(lines, "", LastUpdateStyle.Render(f.hint))
+	return strings.Join(lines, "\n")
+}
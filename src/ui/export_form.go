@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ExportValues are the choices made in the export dialog
+type ExportValues struct {
+	From     string
+	To       string
+	Project  string
+	Customer string
+	Task     string
+	Format   string
+	File     string // Empty for the default name
+}
+
+// exportFields label the inputs of the export dialog, in focus order
+var exportFields = []string{"From", "To", "Project", "Customer", "Task", "Format", "File"}
+
+// ExportForm asks for the date range, filters, format and file of an export
+type ExportForm struct {
+	inputs []textinput.Model
+	focus  int
+	err    string
+	hint   string
+}
+
+// NewExportForm creates the dialog with the range and format preset
+func NewExportForm(from, to, format string) *ExportForm {
+	f := &ExportForm{inputs: make([]textinput.Model, len(exportFields))}
+	for i := range f.inputs {
+		f.inputs[i] = textinput.New()
+	}
+	f.inputs[0].SetValue(from)
+	f.inputs[0].Placeholder = "YYYY-MM-DD, today or a weekday"
+	f.inputs[1].SetValue(to)
+	f.inputs[1].Placeholder = "last day, default today"
+	f.inputs[2].Placeholder = "ID or part of the name, optional"
+	f.inputs[3].Placeholder = "ID or part of the name, optional"
+	f.inputs[4].Placeholder = "ID or part of the name, optional"
+	f.inputs[5].SetValue(format)
+	f.inputs[5].Placeholder = "csv, json, ndjson or ics"
+	f.inputs[6].Placeholder = "default moco-<from>-<to>.<format> here"
+	f.SetKeyHints("enter", "esc", "tab")
+	f.focusCurrent()
+	return f
+}
+
+func (f *ExportForm) focusCurrent() {
+	for i := range f.inputs {
+		f.inputs[i].Blur()
+	}
+	f.inputs[f.focus].Focus()
+}
+
+// PrevField moves the focus to the previous field
+func (f *ExportForm) PrevField() {
+	f.focus = (f.focus - 1 + len(f.inputs)) % len(f.inputs)
+	f.focusCurrent()
+}
+
+// NextField moves the focus to the next field
+func (f *ExportForm) NextField() {
+	f.focus = (f.focus + 1) % len(f.inputs)
+	f.focusCurrent()
+}
+
+// Update edits the focused field
+func (f *ExportForm) Update(msg tea.KeyMsg) {
+	f.inputs[f.focus], _ = f.inputs[f.focus].Update(msg)
+}
+
+// SetKeyHints sets the keys named in the help line
+func (f *ExportForm) SetKeyHints(submit, back, next string) {
+	f.hint = fmt.Sprintf("'%s' export, '%s' cancel, '%s' next field", submit, back, next)
+}
+
+// Values returns the choices as entered
+func (f *ExportForm) Values() ExportValues {
+	value := func(i int) string { return strings.TrimSpace(f.inputs[i].Value()) }
+	return ExportValues{
+		From:     value(0),
+		To:       value(1),
+		Project:  value(2),
+		Customer: value(3),
+		Task:     value(4),
+		Format:   strings.ToLower(value(5)),
+		File:     value(6),
+	}
+}
+
+// SetError shows why the entries can't be exported, or what needs confirming
+func (f *ExportForm) SetError(err string) {
+	f.err = err
+}
+
+func (f *ExportForm) View() string {
+	lines := []string{TitleStyle.Render("Export time entries")}
+	for i, label := range exportFields {
+		lines = append(lines, fmt.Sprintf("%-9s %s", label+":", f.inputs[i].View()))
+	}
+	if f.err != "" {
+		lines = append(lines, ErrorStyle.Render("Error: "+f.err))
+	}
+	lines = append(lines, "", LastUpdateStyle.Render(f.hint))
+	return strings.Join(lines, "\n")
+}
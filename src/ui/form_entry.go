@@ -2,21 +2,42 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/denwerk/moco/src/types"
+)
+
+// Form fields in focus order
+const (
+	fieldDate = iota
+	fieldHours
+	fieldDesc
+	fieldTicket
+	fieldBillable
+	fieldTag
+	fieldCount
 )
 
 type FormEntry struct {
-	dateInput    textinput.Model
-	hoursInput   textinput.Model
-	descInput    textinput.Model
-	focusedInput int
-	width        int
-	height       int
-	taskTitle    string
+	dateInput       textinput.Model
+	hoursInput      textinput.Model
+	descInput       textinput.Model
+	ticketInput     textinput.Model
+	tagInput        textinput.Model
+	billable        bool
+	billableFocused bool // The checkbox has no text input to track focus
+	focusedInput    int
+	width           int
+	height          int
+	taskTitle       string
+	editingID       int // ID of the time entry being edited, 0 for a new entry
+	submitKey       string
+	backKey         string
+	nextPaneKey     string
 }
 
 func NewFormEntry() FormEntry {
@@ -30,11 +51,23 @@ func NewFormEntry() FormEntry {
 	descInput := textinput.New()
 	descInput.Placeholder = "Enter description"
 
+	ticketInput := textinput.New()
+	ticketInput.Placeholder = "ABC-123, #456 or URL (detected from description if empty)"
+
+	tagInput := textinput.New()
+	tagInput.Placeholder = "Optional tag"
+
 	return FormEntry{
 		dateInput:    dateInput,
 		hoursInput:   hoursInput,
 		descInput:    descInput,
+		ticketInput:  ticketInput,
+		tagInput:     tagInput,
+		billable:     true,
 		focusedInput: 0,
+		submitKey:    "enter",
+		backKey:      "esc",
+		nextPaneKey:  "tab",
 	}
 }
 
@@ -44,12 +77,12 @@ func (f *FormEntry) Update(msg tea.Msg) tea.Cmd {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "up":
-			f.focusedInput = (f.focusedInput - 1 + 3) % 3
-			f.focusCurrentInput()
-		case "down":
-			f.focusedInput = (f.focusedInput + 1) % 3
-			f.focusCurrentInput()
+		case " ":
+			if f.focusedInput == fieldBillable {
+				f.billable = !f.billable
+			} else {
+				f.updateInputs(msg)
+			}
 		default:
 			f.updateInputs(msg)
 		}
@@ -59,32 +92,56 @@ func (f *FormEntry) Update(msg tea.Msg) tea.Cmd {
 }
 
 func (f *FormEntry) View() string {
+	title := TitleStyle.Render("New Time Entry")
+	if f.editingID != 0 {
+		title = EditingStyle.Render(fmt.Sprintf("Editing #%d", f.editingID))
+	}
+
 	form := lipgloss.JoinVertical(lipgloss.Left,
-		TitleStyle.Render("New Time Entry"),
+		title,
 		fmt.Sprintf("Task: %s", f.taskTitle),
 		fmt.Sprintf("Date: %s", f.dateInput.View()),
 		fmt.Sprintf("Hours: %s", f.hoursInput.View()),
 		fmt.Sprintf("Description: %s", f.descInput.View()),
-		"Press 'enter' to submit, 'esc' to cancel, 'tab' to switch between panes.",
+		fmt.Sprintf("Ticket: %s", f.ticketInput.View()),
+		fmt.Sprintf("Billable: %s", f.billableView()),
+		fmt.Sprintf("Tag: %s", f.tagInput.View()),
+		f.helpText(),
 	)
 
 	return form
 }
 
+// PrevField moves the focus to the previous field
+func (f *FormEntry) PrevField() {
+	f.focusedInput = (f.focusedInput - 1 + fieldCount) % fieldCount
+	f.focusCurrentInput()
+}
+
+// NextField moves the focus to the next field
+func (f *FormEntry) NextField() {
+	f.focusedInput = (f.focusedInput + 1) % fieldCount
+	f.focusCurrentInput()
+}
+
 func (f *FormEntry) focusCurrentInput() {
 	// Blur all inputs first
-	f.dateInput.Blur()
-	f.hoursInput.Blur()
-	f.descInput.Blur()
+	f.BlurAll()
 
 	// Focus the current input
 	switch f.focusedInput {
-	case 0:
+	case fieldDate:
 		f.dateInput.Focus()
-	case 1:
+	case fieldHours:
 		f.hoursInput.Focus()
-	case 2:
+	case fieldDesc:
 		f.descInput.Focus()
+	case fieldTicket:
+		f.ticketInput.Focus()
+	case fieldBillable:
+		f.billableFocused = true
+	case fieldTag:
+		f.tagInput.Focus()
 	}
 }
 
@@ -92,18 +149,80 @@ func (f *FormEntry) updateInputs(msg tea.KeyMsg) {
 	f.dateInput, _ = f.dateInput.Update(msg)
 	f.hoursInput, _ = f.hoursInput.Update(msg)
 	f.descInput, _ = f.descInput.Update(msg)
+	f.ticketInput, _ = f.ticketInput.Update(msg)
+	f.tagInput, _ = f.tagInput.Update(msg)
+}
+
+func (f *FormEntry) billableView() string {
+	box := "[ ] no"
+	if f.billable {
+		box = "[x] yes"
+	}
+	if f.billableFocused {
+		return SelectedStyle.Render(box) + LastUpdateStyle.Render(" (space to toggle)")
+	}
+	return box
 }
 
 func (f *FormEntry) BlurAll() {
 	f.dateInput.Blur()
 	f.hoursInput.Blur()
 	f.descInput.Blur()
+	f.ticketInput.Blur()
+	f.tagInput.Blur()
+	f.billableFocused = false
 }
 
+// Clear resets the form for a new entry. Billable keeps its value, which
+// follows the selected task.
 func (f *FormEntry) Clear() {
 	f.dateInput.SetValue(time.Now().Format("2006-01-02"))
 	f.hoursInput.SetValue("")
 	f.descInput.SetValue("")
+	f.ticketInput.SetValue("")
+	f.tagInput.SetValue("")
+	f.editingID = 0
+}
+
+// Billable returns whether the entry is marked billable
+func (f *FormEntry) Billable() bool {
+	return f.billable
+}
+
+// SetBillable sets the billable flag, e.g. to the default of a task
+func (f *FormEntry) SetBillable(billable bool) {
+	f.billable = billable
+}
+
+// Ticket returns the entered ticket reference
+func (f *FormEntry) Ticket() string {
+	return f.ticketInput.Value()
+}
+
+// Tag returns the entered tag
+func (f *FormEntry) Tag() string {
+	return f.tagInput.Value()
+}
+
+// EditEntry loads an existing time entry into the form for editing
+func (f *FormEntry) EditEntry(entry types.TimeEntry) {
+	f.dateInput.SetValue(entry.Date)
+	f.hoursInput.SetValue(strconv.FormatFloat(entry.Hours, 'f', -1, 64))
+	f.descInput.SetValue(entry.Description)
+	f.tagInput.SetValue(entry.Tag)
+	if entry.RemoteURL != "" {
+		f.ticketInput.SetValue(entry.RemoteURL)
+	} else {
+		f.ticketInput.SetValue(entry.RemoteID)
+	}
+	f.billable = entry.IsBillable()
+	f.taskTitle = entry.Task.Name
+	f.editingID = entry.ID
+}
+
+// EditingID returns the ID of the entry being edited, or 0 for a new entry
+func (f *FormEntry) EditingID() int {
+	return f.editingID
 }
 
 func (f *FormEntry) GetValues() (string, string, string) {
@@ -118,3 +237,15 @@ func (f *FormEntry) SetSize(width, height int) {
 func (f *FormEntry) SetTaskTitle(title string) {
 	f.taskTitle = title
 }
+
+// SetKeyHints sets the keys named in the help text
+func (f *FormEntry) SetKeyHints(submit, back, nextPane string) {
+	f.submitKey, f.backKey, f.nextPaneKey = submit, back, nextPane
+}
+
+func (f *FormEntry) helpText() string {
+	if f.editingID != 0 {
+		return fmt.Sprintf("Press '%s' to save changes, '%s' to stop editing, '%s' to switch between panes.", f.submitKey, f.backKey, f.nextPaneKey)
+	}
+	return fmt.Sprintf("Press '%s' to submit, '%s' to cancel, '%s' to switch between panes.", f.submitKey, f.backKey, f.nextPaneKey)
+}
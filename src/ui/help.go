@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HelpSection lists the bindings active in one part of the screen
+type HelpSection struct {
+	Title string
+	Rows  []HelpRow
+}
+
+// HelpRow is one action with the keys bound to it
+type HelpRow struct {
+	Keys string
+	Help string
+}
+
+// RenderHelp renders the key bindings, one section after another
+func RenderHelp(sections []HelpSection, closeKeys string) string {
+	width := 0
+	for _, section := range sections {
+		for _, row := range section.Rows {
+			width = max(width, len(row.Keys))
+		}
+	}
+
+	lines := []string{TitleStyle.Render("Keys")}
+	for _, section := range sections {
+		if len(section.Rows) == 0 {
+			continue
+		}
+		lines = append(lines, "", HeaderStyle.Render(section.Title))
+		for _, row := range section.Rows {
+			lines = append(lines, fmt.Sprintf("  %-*s  %s", width, row.Keys, row.Help))
+		}
+	}
+	lines = append(lines, "", LastUpdateStyle.Render(fmt.Sprintf("Press %s or any other key to close. Change keys in the [keys] table of the config file.", closeKeys)))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueueItem is a queued write as shown in the queue view
+type QueueItem struct {
+	Summary string
+	Status  string
+	Error   string
+}
+
+// RenderQueue renders the offline write queue with the cursor on one item.
+// hint lists the keys that act on it.
+func RenderQueue(items []QueueItem, cursor int, hint string) string {
+	if len(items) == 0 {
+		return LastUpdateStyle.Render("The write queue is empty.")
+	}
+
+	var lines []string
+	for i, item := range items {
+		status := QueuePendingStyle.Render(fmt.Sprintf("[%s]", item.Status))
+		if item.Status != "pending" {
+			status = ErrorStyle.UnsetPaddingTop().Render(fmt.Sprintf("[%s]", item.Status))
+		}
+		line := fmt.Sprintf("%s %s", status, item.Summary)
+		if i == cursor {
+			line = SelectedItemStyle.Render("> " + line)
+		} else {
+			line = ItemStyle.Render(line)
+		}
+		lines = append(lines, line)
+		if item.Error != "" {
+			lines = append(lines, ItemStyle.Render("    "+LastUpdateStyle.Render(item.Error)))
+		}
+	}
+
+	lines = append(lines, "", LastUpdateStyle.Render(hint))
+	return strings.Join(lines, "\n")
+}
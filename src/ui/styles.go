@@ -5,61 +5,168 @@ import (
 	"io"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
-// Styles used throughout the application
+// Styles used throughout the application, see ApplyTheme
 var (
 	// DocStyle is used for the main layout
 	DocStyle = lipgloss.NewStyle().Margin(1, 2)
 
 	// TitleStyle is used for headers
-	TitleStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("63"))
+	TitleStyle lipgloss.Style
 
-	HeaderStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("3"))
+	HeaderStyle lipgloss.Style
 
-	TotalStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("6"))
+	TotalStyle lipgloss.Style
 
 	// ItemStyle is used for list items
 	ItemStyle = lipgloss.NewStyle().PaddingLeft(4)
 
 	// SelectedItemStyle is used for selected list items
-	SelectedItemStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("255"))
+	SelectedItemStyle lipgloss.Style
 
 	// ErrorStyle is used for error messages
-	ErrorStyle = lipgloss.NewStyle().PaddingTop(1).Foreground(lipgloss.Color("196"))
+	ErrorStyle lipgloss.Style
 
 	// SuccessStyle is used for success messages
-	SuccessStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("46"))
+	SuccessStyle lipgloss.Style
 
 	// LastUpdateStyle is used for the last update timestamp
-	LastUpdateStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	LastUpdateStyle lipgloss.Style
 
 	// PaneStyle is used for panes
-	PaneStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("240")).
-			Padding(0, 2)
+	PaneStyle lipgloss.Style
 
 	// FocusedPaneStyle is used for focused panes
-	FocusedPaneStyle = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("62")).
-				Padding(0, 2)
+	FocusedPaneStyle lipgloss.Style
 
 	// ConfirmDialogStyle is used for confirmation dialogs
-	ConfirmDialogStyle = lipgloss.NewStyle().
-				BorderStyle(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("196")). // Red border for warning
-				Padding(1, 0)
-
-	SelectedStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#00FF00")).
-			Bold(true)
+	ConfirmDialogStyle lipgloss.Style
+
+	// EditingStyle marks the form title while an existing entry is edited
+	EditingStyle lipgloss.Style
+
+	// TimerStyle is used for the running timer
+	TimerStyle lipgloss.Style
+
+	// QueuePendingStyle is used for writes waiting in the offline queue
+	QueuePendingStyle lipgloss.Style
+
+	SelectedStyle lipgloss.Style
+
+	// tableStyles are used for the time entries table
+	tableStyles table.Styles
+
+	// listStyles are used for the task list, listHelpKeyStyle for the
+	// keys in its help line
+	listStyles       list.Styles
+	listHelpKeyStyle lipgloss.Style
 )
 
+func init() {
+	ApplyTheme(Themes["dark"])
+}
+
+// ApplyTheme sets the styles to the colours of t. Tables and lists created
+// before keep their old colours.
+func ApplyTheme(t Theme) {
+	TitleStyle = foreground(lipgloss.NewStyle().Bold(true), t.Title)
+	HeaderStyle = foreground(lipgloss.NewStyle().Bold(true), t.Header)
+	TotalStyle = foreground(lipgloss.NewStyle().Bold(true), t.Total)
+	SelectedItemStyle = foreground(lipgloss.NewStyle().PaddingLeft(2), t.Selected)
+	ErrorStyle = foreground(lipgloss.NewStyle().PaddingTop(1), t.Error)
+	SuccessStyle = foreground(lipgloss.NewStyle(), t.Success)
+	LastUpdateStyle = foreground(lipgloss.NewStyle(), t.Muted)
+	EditingStyle = foreground(lipgloss.NewStyle().Bold(true), t.Editing)
+	TimerStyle = foreground(lipgloss.NewStyle().Bold(true), t.Timer)
+	QueuePendingStyle = foreground(lipgloss.NewStyle(), t.Queue)
+	SelectedStyle = foreground(lipgloss.NewStyle().Bold(true), t.Highlight)
+
+	PaneStyle = borderColor(lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 2), t.Border)
+	FocusedPaneStyle = borderColor(lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 2), t.Focus)
+	if t.Focus == "" {
+		// Without colour the focused pane stands out by its border alone
+		FocusedPaneStyle = FocusedPaneStyle.Border(lipgloss.ThickBorder())
+	}
+	ConfirmDialogStyle = borderColor(lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder()).Padding(1, 0), t.Warning)
+
+	s := table.DefaultStyles()
+	s.Header = foreground(s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderBottom(true), t.TableHeader)
+	s.Header = borderColor(s.Header, t.TableBorder)
+	// Replaced rather than adjusted, the default has a colour of its own
+	s.Selected = foreground(lipgloss.NewStyle(), t.TableSelected)
+	if t.TableSelectedBg != "" {
+		s.Selected = s.Selected.Background(lipgloss.Color(t.TableSelectedBg))
+	} else {
+		s.Selected = s.Selected.Reverse(true)
+	}
+	s.Cell = foreground(s.Cell, t.TableText)
+	tableStyles = s
+
+	// The list defaults all carry colours, so every style that shows is
+	// replaced
+	l := list.DefaultStyles()
+	l.Title = foreground(lipgloss.NewStyle().Bold(true).Padding(0, 1), t.Title)
+	l.Spinner = foreground(lipgloss.NewStyle(), t.Muted)
+	l.FilterPrompt = foreground(lipgloss.NewStyle(), t.Highlight)
+	l.FilterCursor = foreground(lipgloss.NewStyle(), t.Highlight)
+	l.StatusBar = foreground(lipgloss.NewStyle().Padding(0, 0, 1, 2), t.Muted)
+	l.StatusEmpty = foreground(lipgloss.NewStyle(), t.Muted)
+	l.StatusBarActiveFilter = lipgloss.NewStyle().Bold(true)
+	l.StatusBarFilterCount = foreground(lipgloss.NewStyle(), t.Muted)
+	l.NoItems = foreground(lipgloss.NewStyle(), t.Muted)
+	l.ArabicPagination = foreground(lipgloss.NewStyle(), t.Muted)
+	l.ActivePaginationDot = lipgloss.NewStyle().Bold(true).SetString("•")
+	l.InactivePaginationDot = foreground(lipgloss.NewStyle(), t.Muted).SetString("•")
+	l.DividerDot = foreground(lipgloss.NewStyle(), t.Muted).SetString(" • ")
+	listStyles = l
+	listHelpKeyStyle = foreground(lipgloss.NewStyle(), t.Header)
+}
+
+// NewTaskList creates the task list in the colours of the current theme
+func NewTaskList(items []list.Item) list.Model {
+	l := list.New(items, ItemDelegate{}, 0, 0)
+	l.Styles = listStyles
+	// list.New copies some of the default styles into its parts
+	l.FilterInput.PromptStyle = listStyles.FilterPrompt
+	l.FilterInput.Cursor.Style = listStyles.FilterCursor
+	l.Paginator.ActiveDot = listStyles.ActivePaginationDot.String()
+	l.Paginator.InactiveDot = listStyles.InactivePaginationDot.String()
+	l.Help.Styles = help.Styles{
+		Ellipsis:       listStyles.NoItems,
+		ShortKey:       listHelpKeyStyle,
+		ShortDesc:      listStyles.NoItems,
+		ShortSeparator: listStyles.NoItems,
+		FullKey:        listHelpKeyStyle,
+		FullDesc:       listStyles.NoItems,
+		FullSeparator:  listStyles.NoItems,
+	}
+	return l
+}
+
+// foreground sets the text colour of style, unless color is empty
+func foreground(style lipgloss.Style, color string) lipgloss.Style {
+	if color == "" {
+		return style
+	}
+	return style.Foreground(lipgloss.Color(color))
+}
+
+// borderColor sets the border colour of style, unless color is empty
+func borderColor(style lipgloss.Style, color string) lipgloss.Style {
+	if color == "" {
+		return style
+	}
+	return style.BorderForeground(lipgloss.Color(color))
+}
+
 type ItemDelegate struct{}
 
 func (d ItemDelegate) Height() int                             { return 1 }
@@ -2,12 +2,11 @@ package ui
 
 import (
 	"fmt"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/table"
-	"github.com/charmbracelet/lipgloss"
+	"github.com/denwerk/moco/src/report"
 	"github.com/denwerk/moco/src/types"
 )
 
@@ -22,44 +21,39 @@ var dayTranslations = map[string]string{
 	"Sunday":    "Sonntag",
 }
 
-// CreateTimeEntriesTable creates a new table with the given time entries
-func CreateTimeEntriesTable(entries []types.TimeEntry, height int) table.Model {
-	// Group entries by date
-	entriesByDate := make(map[string][]types.TimeEntry)
-	for _, entry := range entries {
-		date := entry.Date
-		entriesByDate[date] = append(entriesByDate[date], entry)
-	}
-
+// CreateTimeEntriesTable creates a new table with the given time entries.
+// Entries whose ID is in marked are flagged, e.g. for copying. The returned
+// slice maps each table row to its index in entries, or -1 for date
+// headers, totals and separators.
+func CreateTimeEntriesTable(entries []types.TimeEntry, marked map[int]bool, height int) (table.Model, []int) {
 	// Create table columns
 	columns := []table.Column{
 		{Title: "Entry", Width: 30},
 		{Title: "Hours", Width: 12},
-		{Title: "Task", Width: 40},
+		{Title: "Task", Width: 30},
+		{Title: "Billable", Width: 8},
+		{Title: "Tag", Width: 12},
+		{Title: "Ticket", Width: 12},
 	}
 
 	// Create rows with date headers
 	var rows []table.Row
+	var rowEntries []int
 
-	// Get sorted dates (latest first)
-	var dates []string
-	for date := range entriesByDate {
-		dates = append(dates, date)
-	}
-	sort.Sort(sort.Reverse(sort.StringSlice(dates)))
-
-	// Add rows in date order
-	for _, date := range dates {
-		entries := entriesByDate[date]
+	// Add rows per day, latest first
+	days := report.ByDay(entries)
+	for d := len(days) - 1; d >= 0; d-- {
+		day := days[d]
+		date := day.Key
 		// Parse date and format in German style with day of week
 		parsedDate, err := time.Parse("2006-01-02", date)
 		if err != nil {
 			// If parsing fails, use the original date
 			rows = append(rows, table.Row{
 				TitleStyle.Render(date),
-				"",
-				"",
+				"", "", "", "", "",
 			})
+			rowEntries = append(rowEntries, -1)
 		} else {
 			// Format date in German style: "Monday, 02.01.2006"
 			formattedDate := parsedDate.Format("Monday, 02.01.2006")
@@ -73,35 +67,45 @@ func CreateTimeEntriesTable(entries []types.TimeEntry, height int) table.Model {
 			}
 			rows = append(rows, table.Row{
 				HeaderStyle.Render(formattedDate),
-				"",
-				"",
+				"", "", "", "", "",
 			})
+			rowEntries = append(rowEntries, -1)
 		}
 
 		// Add entries for this date
-		for _, entry := range entries {
+		for _, i := range day.Entries {
+			entry := entries[i]
+			description := entry.Description
+			if entry.TimerStartedAt != nil {
+				description = "⏱ " + description
+			}
+			if marked[entry.ID] {
+				description = "• " + description
+			}
 			rows = append(rows, table.Row{
-				entry.Description,
+				description,
 				fmt.Sprintf("%.2f", entry.Hours),
 				entry.Task.Name,
+				billableLabel(entry),
+				entry.Tag,
+				entry.RemoteID,
 			})
+			rowEntries = append(rowEntries, i)
 		}
 
 		// Add total hours for the day
-		totalHours := 0.0
-		for _, entry := range entries {
-			totalHours += entry.Hours
-		}
 		rows = append(rows, table.Row{
 			TotalStyle.Render("Total:"),
-			TotalStyle.Render(fmt.Sprintf("%.2f", totalHours)),
-			"",
+			TotalStyle.Render(fmt.Sprintf("%.2f", day.Hours)),
+			"", "", "", "",
 		})
+		rowEntries = append(rowEntries, -1)
 
 		// Add separator row
 		rows = append(rows, table.Row{
-			"", "", "",
+			"", "", "", "", "", "",
 		})
+		rowEntries = append(rowEntries, -1)
 	}
 
 	t := table.New(
@@ -111,21 +115,13 @@ func CreateTimeEntriesTable(entries []types.TimeEntry, height int) table.Model {
 		table.WithHeight(height),
 	)
 
-	// Customize table styles
-	s := table.DefaultStyles()
-	s.Header = s.Header.
-		BorderStyle(lipgloss.NormalBorder()).
-		BorderForeground(lipgloss.Color("63")).
-		BorderBottom(true).
-		BorderBottomForeground(lipgloss.Color("63")).
-		Foreground(lipgloss.Color("255"))
-	s.Selected = s.Selected.
-		Foreground(lipgloss.Color("255")).
-		Background(lipgloss.Color("63")).
-		Bold(false)
-	s.Cell = s.Cell.
-		Foreground(lipgloss.Color("255"))
+	t.SetStyles(tableStyles)
+	return t, rowEntries
+}
 
-	t.SetStyles(s)
-	return t
+func billableLabel(entry types.TimeEntry) string {
+	if entry.IsBillable() {
+		return "yes"
+	}
+	return "no"
 }
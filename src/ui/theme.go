@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Theme holds the colours of the UI by role. A colour is an ANSI number
+// ("63") or a hex value ("#5f5fff"); empty leaves the terminal's own colour.
+type Theme struct {
+	Name            string
+	Title           string // Pane and dialog titles
+	Header          string // Project headers and labels in the copy dialog
+	Total           string // Daily totals
+	Selected        string // Selected task
+	Error           string
+	Success         string
+	Muted           string // Hints, timestamps and other secondary text
+	Border          string // Unfocused panes
+	Focus           string // The focused pane
+	Warning         string // Border of the delete confirmation and prompts
+	Editing         string // Form title while editing an entry
+	Timer           string // Running timer
+	Queue           string // Writes waiting in the offline queue
+	Highlight       string // Selected entry info and focused checkbox
+	TableHeader     string
+	TableBorder     string
+	TableText       string
+	TableSelected   string // Text of the selected row
+	TableSelectedBg string // Background of the selected row; reversed if empty
+}
+
+// Themes are the built-in themes by name
+var Themes = map[string]Theme{
+	"dark": {
+		Name: "dark", Title: "63", Header: "3", Total: "6", Selected: "255",
+		Error: "196", Success: "46", Muted: "240", Border: "240", Focus: "62",
+		Warning: "196", Editing: "214", Timer: "202", Queue: "214", Highlight: "#00FF00",
+		TableHeader: "255", TableBorder: "63", TableText: "255", TableSelected: "255", TableSelectedBg: "63",
+	},
+	// For light backgrounds such as Solarized Light; text keeps the
+	// terminal's foreground
+	"light": {
+		Name: "light", Title: "25", Header: "94", Total: "30", Selected: "",
+		Error: "160", Success: "28", Muted: "244", Border: "250", Focus: "25",
+		Warning: "160", Editing: "130", Timer: "166", Queue: "130", Highlight: "28",
+		TableHeader: "", TableBorder: "25", TableText: "", TableSelected: "231", TableSelectedBg: "25",
+	},
+	// Bright colours of the basic 16, which every terminal has
+	"high-contrast": {
+		Name: "high-contrast", Title: "15", Header: "11", Total: "14", Selected: "15",
+		Error: "9", Success: "10", Muted: "7", Border: "7", Focus: "11",
+		Warning: "9", Editing: "11", Timer: "11", Queue: "11", Highlight: "10",
+		TableHeader: "15", TableBorder: "15", TableText: "15", TableSelected: "0", TableSelectedBg: "11",
+	},
+	// No colours at all; selection is reversed and the focused pane gets a
+	// thick border
+	"monochrome": {Name: "monochrome"},
+}
+
+// ThemeNames returns the names of the built-in themes, sorted
+func ThemeNames() []string {
+	var names []string
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// colors maps the names used in the [colors] table of the config file to
+// the fields of t
+func (t *Theme) colors() map[string]*string {
+	return map[string]*string{
+		"title":             &t.Title,
+		"header":            &t.Header,
+		"total":             &t.Total,
+		"selected":          &t.Selected,
+		"error":             &t.Error,
+		"success":           &t.Success,
+		"muted":             &t.Muted,
+		"border":            &t.Border,
+		"focus":             &t.Focus,
+		"warning":           &t.Warning,
+		"editing":           &t.Editing,
+		"timer":             &t.Timer,
+		"queue":             &t.Queue,
+		"highlight":         &t.Highlight,
+		"table_header":      &t.TableHeader,
+		"table_border":      &t.TableBorder,
+		"table_text":        &t.TableText,
+		"table_selected":    &t.TableSelected,
+		"table_selected_bg": &t.TableSelectedBg,
+	}
+}
+
+// hexColorPattern matches #rgb and #rrggbb
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// WithColors returns t with the colours of overrides, keyed by role as in
+// the [colors] table. "none" removes a colour.
+func (t Theme) WithColors(overrides map[string]string) (Theme, error) {
+	fields := t.colors()
+	var problems []string
+	roles := make([]string, 0, len(overrides))
+	for role := range overrides {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		field, ok := fields[role]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown colour %q", role))
+			continue
+		}
+		value := overrides[role]
+		if value == "none" {
+			value = ""
+		} else if !validColor(value) {
+			problems = append(problems, fmt.Sprintf("%s: %q is not an ANSI number (0-255) or a hex colour like #5f5fff", role, value))
+			continue
+		}
+		*field = value
+	}
+	if len(problems) > 0 {
+		return t, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return t, nil
+}
+
+func validColor(value string) bool {
+	if hexColorPattern.MatchString(value) {
+		return true
+	}
+	n, err := strconv.Atoi(value)
+	return err == nil && n >= 0 && n <= 255
+}
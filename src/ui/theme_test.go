@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestApplyThemeMonochrome(t *testing.T) {
+	defer ApplyTheme(Themes["dark"])
+	ApplyTheme(Themes["monochrome"])
+
+	colored := map[string]lipgloss.Style{
+		"table header":   tableStyles.Header,
+		"table cell":     tableStyles.Cell,
+		"table selected": tableStyles.Selected,
+		"list title":     listStyles.Title,
+		"list status":    listStyles.StatusBar,
+		"title":          TitleStyle,
+		"focused pane":   FocusedPaneStyle,
+	}
+	for name, style := range colored {
+		if _, ok := style.GetForeground().(lipgloss.NoColor); !ok {
+			t.Errorf("%s has foreground %v", name, style.GetForeground())
+		}
+		if _, ok := style.GetBackground().(lipgloss.NoColor); !ok {
+			t.Errorf("%s has background %v", name, style.GetBackground())
+		}
+	}
+	if !tableStyles.Selected.GetReverse() {
+		t.Error("selected row isn't reversed")
+	}
+
+	l := NewTaskList(nil)
+	if _, ok := l.Help.Styles.ShortDesc.GetForeground().(lipgloss.NoColor); !ok {
+		t.Errorf("list help has foreground %v", l.Help.Styles.ShortDesc.GetForeground())
+	}
+}
+
+func TestWithColors(t *testing.T) {
+	theme, err := Themes["dark"].WithColors(map[string]string{
+		"title":             "#5f5fff",
+		"error":             "9",
+		"table_selected_bg": "none",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme.Title != "#5f5fff" || theme.Error != "9" || theme.TableSelectedBg != "" {
+		t.Errorf("theme = %+v", theme)
+	}
+	if Themes["dark"].Title != "63" {
+		t.Error("WithColors changed the built-in theme")
+	}
+
+	_, err = Themes["dark"].WithColors(map[string]string{"titel": "1", "error": "256", "muted": "#12"})
+	if err == nil {
+		t.Fatal("invalid colours were accepted")
+	}
+	for _, want := range []string{`"titel"`, "error", "muted"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %s", err, want)
+		}
+	}
+}
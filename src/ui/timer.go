@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/denwerk/moco/src/types"
+)
+
+// FormatElapsed formats a duration as h:mm:ss
+func FormatElapsed(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+}
+
+// RenderTimer renders the status line for a running timer
+func RenderTimer(entry *types.TimeEntry, now time.Time) string {
+	if entry == nil {
+		return LastUpdateStyle.Render("No timer running")
+	}
+	label := entry.Task.Name
+	if entry.Description != "" {
+		label += " - " + entry.Description
+	}
+	return TimerStyle.Render(fmt.Sprintf("⏱ %s  %s", FormatElapsed(entry.Elapsed(now)), label))
+}
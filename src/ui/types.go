@@ -12,6 +12,7 @@ type TableEntry struct {
 	ProjectID       int
 	IsProjectHeader bool
 	Position        int
+	Billable        bool // Default for new entries on this task
 }
 
 func (i TableEntry) FilterValue() string { return "" }
@@ -36,6 +37,7 @@ func MapProjectsToItems(projects []types.Project) []list.Item {
 					ProjectID:       project.ID,
 					IsProjectHeader: false,
 					Position:        i,
+					Billable:        project.Billable && task.Billable,
 				})
 				i++
 			}